@@ -0,0 +1,438 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 压缩包按虚拟目录展开：成员以 "archive.zip!/inner/path" 表示，内容不解压到磁盘，直接流入 SM3。
+// 支持 .zip、.tar、.tar.gz / .tgz，以及单文件 .gz。
+
+const archiveSep = "!/"
+
+type archiveKind int
+
+const (
+	archiveNone archiveKind = iota
+	archiveZip
+	archiveTar
+	archiveTarGz
+	archiveGz
+)
+
+func archiveKindOf(name string) archiveKind {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTarGz
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTar
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip
+	case strings.HasSuffix(name, ".gz"):
+		return archiveGz
+	}
+	return archiveNone
+}
+
+// splitArchivePath 拆分虚拟路径为压缩包路径与成员名。
+func splitArchivePath(p string) (archive, member string, ok bool) {
+	for i := strings.Index(p, archiveSep); i >= 0; {
+		if archiveKindOf(p[:i]) != archiveNone {
+			return p[:i], p[i+len(archiveSep):], true
+		}
+		j := strings.Index(p[i+1:], archiveSep)
+		if j < 0 {
+			break
+		}
+		i += 1 + j
+	}
+	return "", "", false
+}
+
+func isArchiveMember(p string) bool {
+	_, _, ok := splitArchivePath(p)
+	return ok
+}
+
+// memberSizes 记录展开时得到的成员大小（压缩包 → 成员 → 大小），供队列汇总进度；由 archivesMu 保护。
+// 压缩包移出句柄缓存或 closeArchives 时清除，每次重新展开时整体替换，不会随运行时间增长。
+var memberSizes = map[string]map[string]int64{}
+
+// archiveMemberSize 返回展开时记录的成员大小。
+func archiveMemberSize(p string) (int64, bool) {
+	archive, member, ok := splitArchivePath(p)
+	if !ok {
+		return 0, false
+	}
+	archivesMu.Lock()
+	defer archivesMu.Unlock()
+	size, ok := memberSizes[archive][member]
+	return size, ok
+}
+
+type archiveMember struct {
+	Name string
+	Size int64
+}
+
+// listArchive 列出压缩包内的普通文件。
+func listArchive(p string) ([]archiveMember, error) {
+	var out []archiveMember
+	switch archiveKindOf(p) {
+	case archiveZip:
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.Mode().IsRegular() {
+				out = append(out, archiveMember{f.Name, int64(f.UncompressedSize64)})
+			}
+		}
+	case archiveTar, archiveTarGz:
+		s, err := openTarStream(p)
+		if err != nil {
+			return nil, err
+		}
+		defer s.Close()
+		for {
+			hdr, err := s.tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, &fs.PathError{Op: "read", Path: p, Err: err}
+			}
+			if hdr.FileInfo().Mode().IsRegular() {
+				out = append(out, archiveMember{tarName(hdr.Name), hdr.Size})
+			}
+		}
+	case archiveGz:
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, &fs.PathError{Op: "read", Path: p, Err: err}
+		}
+		out = append(out, archiveMember{gzMemberName(p, gz), 0})
+	}
+	sizes := make(map[string]int64, len(out))
+	for _, m := range out {
+		sizes[m.Name] = m.Size
+	}
+	archivesMu.Lock()
+	memberSizes[p] = sizes
+	archivesMu.Unlock()
+	return out, nil
+}
+
+func tarName(name string) string {
+	return path.Clean(strings.TrimPrefix(name, "./"))
+}
+
+func gzMemberName(p string, gz *gzip.Reader) string {
+	if gz.Name != "" {
+		return path.Base(filepath.ToSlash(gz.Name))
+	}
+	base := filepath.Base(p)
+	return base[:len(base)-len(".gz")]
+}
+
+type tarStream struct {
+	f  *os.File
+	gz *gzip.Reader
+	tr *tar.Reader
+}
+
+func openTarStream(p string) (*tarStream, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	s := &tarStream{f: f}
+	var r io.Reader = f
+	if archiveKindOf(p) == archiveTarGz {
+		if s.gz, err = gzip.NewReader(f); err != nil {
+			f.Close()
+			return nil, &fs.PathError{Op: "read", Path: p, Err: err}
+		}
+		r = s.gz
+	}
+	s.tr = tar.NewReader(r)
+	return s, nil
+}
+
+func (s *tarStream) Close() error {
+	if s.gz != nil {
+		s.gz.Close()
+	}
+	return s.f.Close()
+}
+
+// openedArchive 是缓存中打开的压缩包：zip 复用目录，各成员可以并发读取；tar 沿数据流顺序前进，
+// 按队列顺序计算成员时整个压缩包只需读一遍，读取成员期间持有该压缩包自己的 mu。
+type openedArchive struct {
+	path  string
+	mu    sync.Mutex // 仅 tar 使用
+	zr    *zip.ReadCloser
+	zfile map[string]*zip.File
+	tar   *tarStream
+
+	// 以下字段由 archivesMu 保护。
+	refs    int    // 正在读取的成员数
+	used    uint64 // 最近一次使用的序号，淘汰时关闭最久未用的
+	evicted bool   // 已移出缓存，最后一个读取者释放时关闭
+}
+
+// maxOpenArchives 是同时保留的压缩包句柄数；超出时关闭最久未用且没有读取者的。
+const maxOpenArchives = 8
+
+var (
+	archivesMu   sync.Mutex
+	openArchives = map[string]*openedArchive{}
+	archiveClock uint64
+)
+
+func (a *openedArchive) close() {
+	if a.zr != nil {
+		a.zr.Close()
+	}
+	if a.tar != nil {
+		a.tar.Close()
+	}
+}
+
+// acquireArchive 返回缓存中的压缩包并增加引用；不在缓存中时打开它。
+// 只有查找和打开（读取 zip 目录）在 archivesMu 下进行，成员内容的读取不持有全局锁。
+func acquireArchive(p string, kind archiveKind) (*openedArchive, error) {
+	archivesMu.Lock()
+	defer archivesMu.Unlock()
+	archiveClock++
+	if a, ok := openArchives[p]; ok {
+		a.refs++
+		a.used = archiveClock
+		return a, nil
+	}
+	a, err := openArchiveFile(p, kind)
+	if err != nil {
+		return nil, err
+	}
+	a.refs, a.used = 1, archiveClock
+	openArchives[p] = a
+	for len(openArchives) > maxOpenArchives {
+		var oldest *openedArchive
+		for _, o := range openArchives {
+			if o.refs == 0 && (oldest == nil || o.used < oldest.used) {
+				oldest = o
+			}
+		}
+		if oldest == nil {
+			break
+		}
+		dropArchiveLocked(oldest)
+	}
+	return a, nil
+}
+
+// releaseArchive 减少引用；已被移出缓存的压缩包在最后一个读取者释放后关闭。
+func releaseArchive(a *openedArchive) {
+	archivesMu.Lock()
+	a.refs--
+	if a.evicted && a.refs == 0 {
+		a.close()
+	}
+	archivesMu.Unlock()
+}
+
+// dropArchiveLocked 把压缩包移出缓存并清掉展开时记录的成员大小；没有读取者时立即关闭。
+func dropArchiveLocked(a *openedArchive) {
+	if openArchives[a.path] == a {
+		delete(openArchives, a.path)
+	}
+	delete(memberSizes, a.path)
+	a.evicted = true
+	if a.refs == 0 {
+		a.close()
+	}
+}
+
+// closeArchives 关闭缓存的压缩包句柄并清空成员大小记录，批量运行结束时调用；
+// 其他批次正在读取的压缩包在读完后关闭。
+func closeArchives() {
+	archivesMu.Lock()
+	for _, a := range openArchives {
+		dropArchiveLocked(a)
+	}
+	clear(memberSizes)
+	archivesMu.Unlock()
+}
+
+// closeArchivesFor 只关闭 paths 中成员所属的压缩包，供并发运行的任务在结束时清理自己用过的压缩包。
+func closeArchivesFor(paths []string) {
+	archivesMu.Lock()
+	defer archivesMu.Unlock()
+	for _, p := range paths {
+		archive, _, ok := splitArchivePath(p)
+		if !ok {
+			continue
+		}
+		delete(memberSizes, archive)
+		if a, ok := openArchives[archive]; ok {
+			dropArchiveLocked(a)
+		}
+	}
+}
+
+// memberReader 在关闭时释放压缩包的引用，tar 还要释放压缩包的 mu。
+type memberReader struct {
+	io.Reader
+	closer  io.Closer
+	archive *openedArchive // gz 为 nil
+	locked  bool
+	once    sync.Once
+}
+
+func (m *memberReader) Close() error {
+	var err error
+	m.once.Do(func() {
+		if m.closer != nil {
+			err = m.closer.Close()
+		}
+		if m.locked {
+			m.archive.mu.Unlock()
+		}
+		if m.archive != nil {
+			releaseArchive(m.archive)
+		}
+	})
+	return err
+}
+
+// openArchiveMember 打开压缩包内的成员，返回内容读取器及其大小（未知时为 0）。
+func openArchiveMember(archive, member string) (io.ReadCloser, int64, error) {
+	virtual := archive + archiveSep + member
+	notFound := &fs.PathError{Op: "open", Path: virtual, Err: fs.ErrNotExist}
+	kind := archiveKindOf(archive)
+	if kind == archiveGz {
+		f, err := os.Open(archive)
+		if err != nil {
+			return nil, 0, err
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, 0, &fs.PathError{Op: "read", Path: archive, Err: err}
+		}
+		if gzMemberName(archive, gz) != member {
+			f.Close()
+			return nil, 0, notFound
+		}
+		return &memberReader{Reader: gz, closer: f}, 0, nil
+	}
+
+	a, err := acquireArchive(archive, kind)
+	if err != nil {
+		return nil, 0, err
+	}
+	if a.zr != nil {
+		zf, ok := a.zfile[member]
+		if !ok {
+			releaseArchive(a)
+			return nil, 0, notFound
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			releaseArchive(a)
+			return nil, 0, &fs.PathError{Op: "read", Path: virtual, Err: err}
+		}
+		return &memberReader{Reader: rc, closer: rc, archive: a}, int64(zf.UncompressedSize64), nil
+	}
+
+	// tar：先从当前位置向后找，找不到再从头扫一遍。
+	a.mu.Lock()
+	fail := func(err error) (io.ReadCloser, int64, error) {
+		a.mu.Unlock()
+		releaseArchive(a)
+		return nil, 0, err
+	}
+	for restarted := false; ; {
+		if a.tar == nil {
+			if a.tar, err = openTarStream(archive); err != nil {
+				a.tar = nil
+				return fail(err)
+			}
+		}
+		hdr, err := a.tar.tr.Next()
+		if err == io.EOF && !restarted {
+			restarted = true
+			a.tar.Close()
+			a.tar = nil
+			continue
+		}
+		if err != nil {
+			// 数据流已不可用，下次从头重新打开。
+			a.tar.Close()
+			a.tar = nil
+			if errors.Is(err, io.EOF) {
+				return fail(notFound)
+			}
+			return fail(&fs.PathError{Op: "read", Path: archive, Err: err})
+		}
+		if hdr.FileInfo().Mode().IsRegular() && tarName(hdr.Name) == member {
+			return &memberReader{Reader: a.tar.tr, archive: a, locked: true}, hdr.Size, nil
+		}
+	}
+}
+
+func openArchiveFile(p string, kind archiveKind) (*openedArchive, error) {
+	a := &openedArchive{path: p}
+	switch kind {
+	case archiveZip:
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return nil, err
+		}
+		a.zr = zr
+		a.zfile = make(map[string]*zip.File, len(zr.File))
+		for _, f := range zr.File {
+			a.zfile[f.Name] = f
+		}
+	case archiveTar, archiveTarGz:
+		s, err := openTarStream(p)
+		if err != nil {
+			return nil, err
+		}
+		a.tar = s
+	default:
+		return nil, &fs.PathError{Op: "open", Path: p, Err: errors.ErrUnsupported}
+	}
+	return a, nil
+}
+
+// computeSM3Member 计算压缩包成员的摘要。
+func computeSM3Member(archive, member string, cancel <-chan struct{}, progress progressFunc) (string, error) {
+	rc, size, err := openArchiveMember(archive, member)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	d := newSM3Digest()
+	if _, err := hashReader(d, cancelReader{rc, cancel}, size, progress); err != nil {
+		return "", &fs.PathError{Op: "read", Path: archive + archiveSep + member, Err: err}
+	}
+	return sm3ToHex(d.finish()), nil
+}
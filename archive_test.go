@@ -0,0 +1,159 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// writeTestArchives 在 dir 中写入内容相同的 zip 与 tar，返回两者路径和成员内容。
+func writeTestArchives(t *testing.T, dir string, n int) (string, string, map[string][]byte) {
+	t.Helper()
+	members := map[string][]byte{}
+	for i := 0; i < n; i++ {
+		data := make([]byte, 1000+i*4099)
+		for j := range data {
+			data[j] = byte(i + j*7)
+		}
+		members[fmt.Sprintf("d/m%02d.bin", i)] = data
+	}
+	zipPath, tarPath := filepath.Join(dir, "a.zip"), filepath.Join(dir, "a.tar")
+	zf, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	tf, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(tf)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("d/m%02d.bin", i)
+		w, err := zw.Create(name)
+		if err == nil {
+			_, err = w.Write(members[name])
+		}
+		if err == nil {
+			err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(members[name])), Typeflag: tar.TypeReg})
+		}
+		if err == nil {
+			_, err = tw.Write(members[name])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{zw, zf, tw, tf} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return zipPath, tarPath, members
+}
+
+func TestArchiveMembersConcurrent(t *testing.T) {
+	defer closeArchives()
+	zipPath, tarPath, members := writeTestArchives(t, t.TempDir(), 12)
+	files, skipped := expandPathsWith([]string{zipPath, tarPath}, expandOptions{archives: true})
+	if len(skipped) != 0 || len(files) != 2*len(members) {
+		t.Fatalf("expanded %d files, skipped %v", len(files), skipped)
+	}
+	for _, f := range files {
+		_, member, _ := splitArchivePath(f)
+		if got := pathSize(f); got != int64(len(members[member])) {
+			t.Errorf("pathSize(%s) = %d, want %d", f, got, len(members[member]))
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*len(files))
+	for round := 0; round < 4; round++ {
+		for _, f := range files {
+			wg.Add(1)
+			go func(f string) {
+				defer wg.Done()
+				_, member, _ := splitArchivePath(f)
+				got, err := computeSM3File(f, defaultHashOptions, nil)
+				if err == nil && got != sm3OneShot(members[member]) {
+					err = fmt.Errorf("digest mismatch")
+				}
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", f, err)
+				}
+			}(f)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestCloseArchivesClearsState(t *testing.T) {
+	dir := t.TempDir()
+	zipPath, tarPath, _ := writeTestArchives(t, dir, 2)
+	files, _ := expandPathsWith([]string{zipPath, tarPath}, expandOptions{archives: true})
+	for _, f := range files {
+		if _, err := computeSM3File(f, defaultHashOptions, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	closeArchivesFor(files[:1])
+	archivesMu.Lock()
+	_, zipOpen := openArchives[zipPath]
+	_, tarSizes := memberSizes[tarPath]
+	archivesMu.Unlock()
+	if zipOpen || !tarSizes {
+		t.Errorf("after closeArchivesFor(zip): zip open %v, tar sizes kept %v", zipOpen, tarSizes)
+	}
+
+	closeArchives()
+	archivesMu.Lock()
+	defer archivesMu.Unlock()
+	if len(openArchives) != 0 || len(memberSizes) != 0 {
+		t.Errorf("after closeArchives: %d open, %d size maps", len(openArchives), len(memberSizes))
+	}
+}
+
+// TestArchiveEviction 打开超过 maxOpenArchives 个压缩包时只保留上限个句柄，读取中的不会被关闭。
+func TestArchiveEviction(t *testing.T) {
+	defer closeArchives()
+	dir := t.TempDir()
+	var held []string
+	for i := 0; i < maxOpenArchives+3; i++ {
+		sub := filepath.Join(dir, fmt.Sprint(i))
+		os.Mkdir(sub, 0755)
+		zipPath, _, _ := writeTestArchives(t, sub, 1)
+		held = append(held, zipPath)
+	}
+	first, _, err := openArchiveMember(held[0], "d/m00.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range held[1:] {
+		rc, _, err := openArchiveMember(p, "d/m00.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+	}
+	archivesMu.Lock()
+	n := len(openArchives)
+	_, firstOpen := openArchives[held[0]]
+	archivesMu.Unlock()
+	if n != maxOpenArchives || !firstOpen {
+		t.Errorf("%d archives open (want %d), in-use archive kept: %v", n, maxOpenArchives, firstOpen)
+	}
+	buf := make([]byte, 10)
+	if _, err := first.Read(buf); err != nil {
+		t.Errorf("reading the held member: %v", err)
+	}
+	first.Close()
+}
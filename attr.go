@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 摘要随文件保存：Linux 写入 user.sm3 扩展属性，Windows 写入 :sm3 备用数据流。
+// 内容为一行文本 "sm3=<hex> size=<字节数> mtime=<UnixNano>"。
+
+const digestAttrName = "sm3"
+
+var errAttrMissing error = localizedError("err.attrMissing")
+
+type storedDigest struct {
+	Digest  string
+	Size    int64
+	ModTime int64
+}
+
+func (s storedDigest) String() string {
+	return fmt.Sprintf("sm3=%s size=%d mtime=%d", s.Digest, s.Size, s.ModTime)
+}
+
+func parseStoredDigest(text string) (storedDigest, error) {
+	var s storedDigest
+	var err error
+	for _, field := range strings.Fields(text) {
+		k, v, _ := strings.Cut(field, "=")
+		switch k {
+		case "sm3":
+			s.Digest, err = parseDigest(v)
+		case "size":
+			s.Size, err = strconv.ParseInt(v, 10, 64)
+		case "mtime":
+			s.ModTime, err = strconv.ParseInt(v, 10, 64)
+		}
+		if err != nil {
+			return s, errors.New(tr("err.attrFormat", text))
+		}
+	}
+	if s.Digest == "" {
+		return s, errors.New(tr("err.attrFormat", text))
+	}
+	return s, nil
+}
+
+// writeDigestAttr 把摘要连同计算时的大小、修改时间写入文件属性。
+func writeDigestAttr(path, digest string, info fs.FileInfo) error {
+	s := storedDigest{Digest: strings.ToLower(digest), Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+	return setDigestAttr(path, info, []byte(s.String()))
+}
+
+func readDigestAttr(path string) (storedDigest, error) {
+	data, err := getDigestAttr(path)
+	if err != nil {
+		return storedDigest{}, err
+	}
+	return parseStoredDigest(string(data))
+}
+
+type attrStatus int
+
+const (
+	attrOK       attrStatus = iota
+	attrMismatch            // 元数据未变但内容摘要不同
+	attrStale               // 文件大小或修改时间已变，属性过期
+	attrMissing             // 没有保存过摘要
+)
+
+func (s attrStatus) String() string {
+	switch s {
+	case attrOK:
+		return "OK"
+	case attrMismatch:
+		return "MISMATCH"
+	case attrStale:
+		return "STALE"
+	}
+	return "MISSING"
+}
+
+type attrCheck struct {
+	Status attrStatus
+	Stored storedDigest
+	Actual string
+}
+
+// verifyDigestAttr 重新计算摘要并与文件属性中保存的值比较。
+func verifyDigestAttr(path string, opt hashOptions, progress progressFunc) (attrCheck, error) {
+	stored, err := readDigestAttr(path)
+	if errors.Is(err, errAttrMissing) {
+		return attrCheck{Status: attrMissing}, nil
+	}
+	if err != nil {
+		return attrCheck{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return attrCheck{}, err
+	}
+	actual, err := computeSM3File(path, opt, progress)
+	if err != nil {
+		return attrCheck{}, err
+	}
+	c := attrCheck{Stored: stored, Actual: actual}
+	switch {
+	case stored.Size != info.Size() || stored.ModTime != info.ModTime().UnixNano():
+		c.Status = attrStale
+	case stored.Digest != actual:
+		c.Status = attrMismatch
+	}
+	return c, nil
+}
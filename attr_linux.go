@@ -0,0 +1,35 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+)
+
+const linuxAttrName = "user." + digestAttrName
+
+func setDigestAttr(path string, info fs.FileInfo, value []byte) error {
+	if err := syscall.Setxattr(path, linuxAttrName, value, 0); err != nil {
+		return &fs.PathError{Op: "setxattr", Path: path, Err: err}
+	}
+	return nil
+}
+
+func getDigestAttr(path string) ([]byte, error) {
+	buf := make([]byte, 256)
+	for {
+		n, err := syscall.Getxattr(path, linuxAttrName, buf)
+		switch {
+		case errors.Is(err, syscall.ENODATA):
+			return nil, errAttrMissing
+		case errors.Is(err, syscall.ERANGE):
+			buf = make([]byte, len(buf)*2)
+			continue
+		case err != nil:
+			return nil, &fs.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		return buf[:n], nil
+	}
+}
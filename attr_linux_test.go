@@ -0,0 +1,64 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDigestAttrRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := verifyDigestAttr(path, defaultHashOptions, nil); err != nil || c.Status != attrMissing {
+		t.Fatalf("before store: %v, %v", c.Status, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	if err := writeDigestAttr(path, abc, info); err != nil {
+		if errors.Is(err, syscall.ENOTSUP) {
+			t.Skipf("file system does not support user xattrs: %v", err)
+		}
+		t.Fatal(err)
+	}
+
+	stored, err := readDigestAttr(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Digest != abc || stored.Size != 3 || stored.ModTime != info.ModTime().UnixNano() {
+		t.Errorf("stored = %+v", stored)
+	}
+	if c, err := verifyDigestAttr(path, defaultHashOptions, nil); err != nil || c.Status != attrOK {
+		t.Errorf("after store: %v, %v", c.Status, err)
+	}
+
+	// 只改修改时间：属性过期。
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := verifyDigestAttr(path, defaultHashOptions, nil); err != nil || c.Status != attrStale {
+		t.Errorf("after mtime change: %v, %v", c.Status, err)
+	}
+
+	// 内容改变但大小、修改时间还原：摘要不符。
+	if err := os.WriteFile(path, []byte("abd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := verifyDigestAttr(path, defaultHashOptions, nil); err != nil || c.Status != attrMismatch {
+		t.Errorf("after content change: %v, %v", c.Status, err)
+	}
+}
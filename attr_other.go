@@ -0,0 +1,16 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+)
+
+func setDigestAttr(path string, info fs.FileInfo, value []byte) error {
+	return &fs.PathError{Op: "setxattr", Path: path, Err: errors.ErrUnsupported}
+}
+
+func getDigestAttr(path string) ([]byte, error) {
+	return nil, &fs.PathError{Op: "getxattr", Path: path, Err: errors.ErrUnsupported}
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// NTFS 备用数据流 file:sm3。写入数据流会更新主文件的修改时间，写完后恢复原值。
+func setDigestAttr(path string, info fs.FileInfo, value []byte) error {
+	if err := os.WriteFile(path+":"+digestAttrName, value, 0644); err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+func getDigestAttr(path string) ([]byte, error) {
+	data, err := os.ReadFile(path + ":" + digestAttrName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errAttrMissing
+	}
+	return data, err
+}
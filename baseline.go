@@ -0,0 +1,429 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 完整性基线（类似 tripwire）：记录目录树中每个文件的路径、大小、权限、修改时间和 SM3，
+// 之后重新扫描并报告新增、删除、内容修改和仅元数据变化的文件。
+// 提供密钥时基线文件带 HMAC-SM3 封印，可以发现基线本身被篡改；不提供密钥时只带 SM3 校验和，
+// 任何人改动后都能重算，只能发现意外损坏。
+
+const (
+	baselineFormat  = "sm3hash-baseline"
+	baselineVersion = 1
+)
+
+// 检查结果的退出码，供 CI / cron 区分告警级别。
+const (
+	exitClean    = 0 // 与基线一致
+	exitError    = 1 // 有文件无法读取
+	exitUsage    = 2
+	exitDrift    = 3 // 有文件新增、删除或内容被修改
+	exitMetadata = 4 // 内容未变，仅权限或修改时间变化
+	exitTampered = 5 // 基线文件无法读取、校验和不符，或 HMAC 封印不符（被篡改）
+)
+
+type baselineEntry struct {
+	Path    string `json:"path"` // 相对根目录，使用 / 分隔
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"`
+	ModTime int64  `json:"mtime"` // UnixNano
+	Digest  string `json:"sm3"`
+}
+
+type baselineFile struct {
+	Format  string          `json:"format"`
+	Version int             `json:"version"`
+	Created time.Time       `json:"created"`
+	Root    string          `json:"root"`
+	Note    string          `json:"note,omitempty"` // 确认人或说明
+	Entries []baselineEntry `json:"entries"`
+	Seal    string          `json:"seal,omitempty"` // "sm3:<hex>"（校验和）或 "hmac-sm3:<hex>"（封印）
+}
+
+var (
+	errBaselineSeal     = localizedError("err.baselineSeal")
+	errBaselineChecksum = localizedError("err.baselineChecksum")
+)
+
+// sealBaseline 对去掉 Seal 字段后的 JSON 计算 HMAC-SM3 封印；key 为空时只算 SM3 校验和。
+func sealBaseline(b baselineFile, key []byte) (string, error) {
+	b.Seal = ""
+	data, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	if len(key) > 0 {
+		return "hmac-sm3:" + hex.EncodeToString(hmacSM3(key, data)), nil
+	}
+	return "sm3:" + sm3Hex(data), nil
+}
+
+// scanBaseline 并行计算 root 下全部文件，返回按路径排序的条目。
+func scanBaseline(root string, opt hashOptions, workers int, bp *batchProgress) ([]baselineEntry, []error) {
+	tree, skipped := collectTree(root)
+	jobs := make([]*hashJob, 0, len(tree))
+	infos := make(map[*hashJob]fs.FileInfo, len(tree))
+	for _, j := range tree {
+		info, err := os.Lstat(j.Path)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		infos[j] = info
+		jobs = append(jobs, j)
+		bp.Add(1, j.Size)
+	}
+	hashFilesParallel(jobs, opt, workers, bp)
+	entries := make([]baselineEntry, 0, len(jobs))
+	for rel, j := range tree {
+		info, ok := infos[j]
+		if !ok {
+			continue
+		}
+		if j.Err != nil {
+			skipped = append(skipped, j.Err)
+			continue
+		}
+		entries = append(entries, baselineEntry{
+			Path:    rel,
+			Size:    info.Size(),
+			Mode:    info.Mode().String(),
+			ModTime: info.ModTime().UnixNano(),
+			Digest:  j.Digest,
+		})
+	}
+	sort.Slice(entries, func(i, k int) bool { return entries[i].Path < entries[k].Path })
+	return entries, skipped
+}
+
+func writeBaseline(path string, b baselineFile, key []byte) error {
+	seal, err := sealBaseline(b, key)
+	if err != nil {
+		return err
+	}
+	b.Seal = seal
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// loadBaseline 读取并校验基线文件；基线带 HMAC 封印时必须提供同一密钥。
+func loadBaseline(path string, key []byte) (baselineFile, error) {
+	var b baselineFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("%s: %v", path, err)
+	}
+	if b.Format != baselineFormat {
+		return b, errors.New(tr("err.notBaseline", path))
+	}
+	if b.Version != baselineVersion {
+		return b, errors.New(tr("err.baselineVersion", path, b.Version))
+	}
+	keyed := strings.HasPrefix(b.Seal, "hmac-sm3:")
+	switch {
+	case keyed && len(key) == 0:
+		return b, errors.New(tr("err.baselineNeedsKey", path))
+	case !keyed && len(key) > 0:
+		// 带密钥检查时不接受无密钥的基线，否则篡改者换成自己重算校验和的基线即可绕过。
+		return b, errors.New(tr("err.baselineUnsealed", path))
+	}
+	want, err := sealBaseline(b, key)
+	if err != nil {
+		return b, err
+	}
+	if !hmac.Equal([]byte(want), []byte(b.Seal)) {
+		if keyed {
+			return b, errBaselineSeal
+		}
+		return b, errBaselineChecksum
+	}
+	return b, nil
+}
+
+type driftStatus int
+
+const (
+	driftAdded driftStatus = iota
+	driftRemoved
+	driftModified
+	driftMetadata
+	driftError
+)
+
+var driftStatusNames = []string{"ADDED", "REMOVED", "MODIFIED", "METADATA", "ERROR"}
+
+func (s driftStatus) String() string { return driftStatusNames[s] }
+
+type driftEntry struct {
+	Status driftStatus
+	Path   string
+	Detail string
+}
+
+// diffBaseline 比较基线与当前扫描结果。
+func diffBaseline(old, cur []baselineEntry) (out []driftEntry, unchanged int) {
+	byPath := make(map[string]baselineEntry, len(old))
+	for _, e := range old {
+		byPath[e.Path] = e
+	}
+	for _, c := range cur {
+		o, ok := byPath[c.Path]
+		if !ok {
+			out = append(out, driftEntry{driftAdded, c.Path, ""})
+			continue
+		}
+		delete(byPath, c.Path)
+		var changes []string
+		if o.Mode != c.Mode {
+			changes = append(changes, fmt.Sprintf("mode %s -> %s", o.Mode, c.Mode))
+		}
+		if o.ModTime != c.ModTime {
+			changes = append(changes, fmt.Sprintf("mtime %s -> %s",
+				time.Unix(0, o.ModTime).Format(time.RFC3339Nano), time.Unix(0, c.ModTime).Format(time.RFC3339Nano)))
+		}
+		switch {
+		case o.Digest != c.Digest || o.Size != c.Size:
+			if o.Size != c.Size {
+				changes = append([]string{fmt.Sprintf("size %d -> %d", o.Size, c.Size)}, changes...)
+			}
+			out = append(out, driftEntry{driftModified, c.Path, strings.Join(changes, ", ")})
+		case len(changes) > 0:
+			out = append(out, driftEntry{driftMetadata, c.Path, strings.Join(changes, ", ")})
+		default:
+			unchanged++
+		}
+	}
+	for p := range byPath {
+		out = append(out, driftEntry{driftRemoved, p, ""})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Status != out[j].Status {
+			return out[i].Status < out[j].Status
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out, unchanged
+}
+
+func driftReport(entries []driftEntry, unchanged int) report {
+	r := report{Columns: []string{"status", "path", "detail"}}
+	var counts [driftError + 1]int64
+	for _, e := range entries {
+		r.add(e.Status.String(), e.Path, e.Detail)
+		counts[e.Status]++
+	}
+	for s, name := range driftStatusNames {
+		r.Summary = append(r.Summary, reportCount{Name: name, Count: counts[s]})
+	}
+	r.Summary = append(r.Summary, reportCount{Name: "UNCHANGED", Count: int64(unchanged)})
+	return r
+}
+
+// driftExitCode 取最严重的一类变化对应的退出码。
+func driftExitCode(entries []driftEntry) int {
+	code := exitClean
+	for _, e := range entries {
+		switch e.Status {
+		case driftAdded, driftRemoved, driftModified:
+			code = exitDrift
+		case driftMetadata:
+			if code == exitClean {
+				code = exitMetadata
+			}
+		case driftError:
+			if code != exitDrift {
+				code = exitError
+			}
+		}
+	}
+	return code
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key = []byte(strings.TrimRight(string(key), "\r\n"))
+	if len(key) == 0 {
+		return nil, errors.New(tr("err.emptyKey", path))
+	}
+	return key, nil
+}
+
+// runBaseline 实现 "sm3hash baseline create|check"。
+func runBaseline(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, tr("baseline.usage"))
+	}
+	if len(args) == 0 || (args[0] != "create" && args[0] != "check") {
+		usage()
+		return exitUsage
+	}
+	create := args[0] == "create"
+	flags := flag.NewFlagSet("sm3hash baseline "+args[0], flag.ContinueOnError)
+	output := flags.String("o", "", tr("flag.baselineOut"))
+	basePath := flags.String("b", "", tr("flag.baselineFile"))
+	keyFile := flags.String("key-file", "", tr("flag.baselineKey"))
+	note := flags.String("note", "", tr("flag.baselineNote"))
+	reportName := flags.String("report", "text", tr("flag.baselineReport"))
+	workers := flags.Int("workers", runtime.NumCPU(), tr("flag.workers"))
+	opt := defaultHashOptions
+	opt.IO.addFlags(flags)
+	progress := flags.String("progress", "auto", tr("flag.progress"))
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	rf, err := parseReportFormat(*reportName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return exitUsage
+	}
+	showProgress, err := progressEnabled(*progress, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return exitUsage
+	}
+	key, err := readKeyFile(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return exitUsage
+	}
+	if create && (*output == "" || flags.NArg() != 1) || !create && (*basePath == "" || flags.NArg() > 1) {
+		flags.Usage()
+		return exitUsage
+	}
+
+	var base baselineFile
+	root := flags.Arg(0)
+	if !create {
+		if base, err = loadBaseline(*basePath, key); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return exitTampered
+		}
+		if len(key) == 0 {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.baselineUnkeyed"))
+		}
+		if root == "" {
+			root = base.Root
+		}
+	}
+	if st, err := os.Stat(root); err != nil || !st.IsDir() {
+		if err == nil {
+			err = errors.New(tr("err.notDir", root))
+		}
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return exitUsage
+	}
+
+	var bp batchProgress
+	bp.Reset()
+	pl := newProgressLine(os.Stderr, &bp, showProgress)
+	pl.start()
+	entries, skipped := scanBaseline(root, opt, *workers, &bp)
+	pl.stop()
+	// 基线文件放在被检查的目录里时不计入。
+	self := *basePath
+	if create {
+		self = *output
+	}
+	if rel, ok := relInRoot(self, root); ok {
+		entries = slices.DeleteFunc(entries, func(e baselineEntry) bool { return e.Path == rel })
+	}
+
+	if create {
+		for _, err := range skipped {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.skipped", classifyError(err), err))
+		}
+		abs, _ := filepath.Abs(root)
+		b := baselineFile{Format: baselineFormat, Version: baselineVersion, Created: time.Now().UTC(), Root: abs, Note: *note, Entries: entries}
+		if err := writeBaseline(*output, b, key); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.writeBaseline", err))
+			return exitError
+		}
+		fmt.Fprintln(os.Stderr, tr("msg.baselineCreated", len(entries), *output))
+		if len(skipped) > 0 {
+			return exitError
+		}
+		return exitClean
+	}
+
+	drift, unchanged := diffBaseline(base.Entries, entries)
+	failed := map[string]bool{}
+	for _, err := range skipped {
+		p := errorPath(err, root)
+		failed[p] = true
+		drift = append(drift, driftEntry{Status: driftError, Path: p, Detail: err.Error()})
+	}
+	// 读取失败的文件不再同时报告为已删除。
+	drift = slices.DeleteFunc(drift, func(e driftEntry) bool { return e.Status == driftRemoved && failed[e.Path] })
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeReport(w, rf, driftReport(drift, unchanged)); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.writeReport", err))
+		return exitError
+	}
+	return driftExitCode(drift)
+}
+
+// errorPath 尽量从错误中取出相对根目录的路径。
+func errorPath(err error, root string) string {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		if rel, ok := relInRoot(pe.Path, root); ok {
+			return rel
+		}
+		return pe.Path
+	}
+	return ""
+}
+
+// relInRoot 返回 p 相对 root 的 / 分隔路径；p 不在 root 之下时 ok 为 false。
+func relInRoot(p, root string) (string, bool) {
+	ap, err1 := filepath.Abs(p)
+	ar, err2 := filepath.Abs(root)
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(ar, ap)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadBaselineSeal(t *testing.T) {
+	dir := t.TempDir()
+	b := baselineFile{Format: baselineFormat, Version: baselineVersion, Created: time.Unix(0, 0).UTC(), Root: dir,
+		Entries: []baselineEntry{{Path: "a", Size: 3, Mode: "-rw-r--r--", Digest: sm3OneShot([]byte("abc"))}}}
+	key, other := []byte("secret"), []byte("other")
+	write := func(name string, key []byte, edit bool) string {
+		p := filepath.Join(dir, name)
+		if err := writeBaseline(p, b, key); err != nil {
+			t.Fatal(err)
+		}
+		if edit {
+			data, _ := os.ReadFile(p)
+			os.WriteFile(p, []byte(strings.Replace(string(data), `"size": 3`, `"size": 4`, 1)), 0644)
+		}
+		return p
+	}
+	tests := []struct {
+		name      string
+		path      string
+		key       []byte
+		wantErr   error // nil 且 fails 为 true 时只要求出错
+		wantFails bool
+	}{
+		{"checksum ok", write("plain", nil, false), nil, nil, false},
+		{"checksum edited", write("plain-edited", nil, true), nil, errBaselineChecksum, true},
+		{"seal ok", write("sealed", key, false), key, nil, false},
+		{"seal edited", write("sealed-edited", key, true), key, errBaselineSeal, true},
+		{"seal wrong key", write("sealed2", key, false), other, errBaselineSeal, true},
+		{"seal without key", write("sealed3", key, false), nil, nil, true},
+		{"key but checksum only", write("plain2", nil, false), key, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadBaseline(tt.path, tt.key)
+			if (err != nil) != tt.wantFails || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("loadBaseline = %v, want %v (fails %v)", err, tt.wantErr, tt.wantFails)
+			}
+		})
+	}
+}
+
+// TestBaselineExitCodes 对每类变化运行一次 baseline check，确认退出码。
+func TestBaselineExitCodes(t *testing.T) {
+	touch := func(p string, data string) {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(root, base string)
+		want   int
+	}{
+		{"clean", func(root, base string) {}, exitClean},
+		{"modified", func(root, base string) { touch(filepath.Join(root, "a"), "changed") }, exitDrift},
+		{"same size modified", func(root, base string) {
+			p := filepath.Join(root, "a")
+			st, _ := os.Stat(p)
+			touch(p, "AAA")
+			os.Chtimes(p, st.ModTime(), st.ModTime())
+		}, exitDrift},
+		{"added", func(root, base string) { touch(filepath.Join(root, "sub", "new"), "new") }, exitDrift},
+		{"removed", func(root, base string) { os.Remove(filepath.Join(root, "sub", "b")) }, exitDrift},
+		{"mode changed", func(root, base string) { os.Chmod(filepath.Join(root, "a"), 0600) }, exitMetadata},
+		{"mtime changed", func(root, base string) {
+			old := time.Now().Add(-time.Hour)
+			os.Chtimes(filepath.Join(root, "a"), old, old)
+		}, exitMetadata},
+		{"modified and mode changed", func(root, base string) {
+			touch(filepath.Join(root, "a"), "changed")
+			os.Chmod(filepath.Join(root, "sub", "b"), 0600)
+		}, exitDrift},
+		{"seal tampered", func(root, base string) {
+			data, _ := os.ReadFile(base)
+			os.WriteFile(base, []byte(strings.Replace(string(data), `"size": 3`, `"size": 7`, 1)), 0644)
+		}, exitTampered},
+		{"baseline missing", func(root, base string) { os.Remove(base) }, exitTampered},
+	}
+	for _, keyed := range []bool{false, true} {
+		for _, tt := range tests {
+			name := tt.name
+			if keyed {
+				name += " (hmac)"
+			}
+			t.Run(name, func(t *testing.T) {
+				dir := t.TempDir()
+				root, base := filepath.Join(dir, "root"), filepath.Join(dir, "base.json")
+				touch(filepath.Join(root, "a"), "aaa")
+				touch(filepath.Join(root, "sub", "b"), "bbb")
+				var keyArgs []string
+				if keyed {
+					keyPath := filepath.Join(dir, "key")
+					touch(keyPath, "secret")
+					keyArgs = []string{"-key-file", keyPath}
+				}
+				create := append([]string{"create", "-o", base, "-progress", "off"}, keyArgs...)
+				if code := runQuiet(t, runBaseline, append(create, root)...); code != exitClean {
+					t.Fatalf("create exit code %d", code)
+				}
+				tt.mutate(root, base)
+				check := append([]string{"check", "-b", base, "-progress", "off"}, keyArgs...)
+				if code := runQuiet(t, runBaseline, append(check, root)...); code != tt.want {
+					t.Errorf("check exit code %d, want %d", code, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestScanBaselineSymlinkedDir 根目录下指向目录的符号链接不进入，也不记为文件。
+func TestScanBaselineSymlinkedDir(t *testing.T) {
+	dir := t.TempDir()
+	root, outside := filepath.Join(dir, "root"), filepath.Join(dir, "outside")
+	for _, p := range []string{filepath.Join(root, "a"), filepath.Join(outside, "x")} {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	var bp batchProgress
+	bp.Reset()
+	entries, skipped := scanBaseline(root, defaultHashOptions, 1, &bp)
+	if len(skipped) != 0 || len(entries) != 1 || entries[0].Path != "a" {
+		t.Errorf("entries %+v, skipped %v; want only a", entries, skipped)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+)
+
+// 性能测试（sm3hash bench）：分别测量压缩函数、不同写入粒度的流式计算，以及不同读取策略和缓冲区大小的
+// 文件计算，以 MB/s 输出表格或 JSON。压缩函数与流式写入对 sm3Impls 中的每个实现各测一遍。
+
+// sm3Impls 是当前可用的压缩函数实现；目前只有纯 Go 的通用实现。
+var sm3Impls = []struct {
+	Name     string
+	compress sm3BlockFunc
+}{
+	{"generic", sm3Compress},
+}
+
+type benchResult struct {
+	Case    string  `json:"case"` // block、write、file
+	Impl    string  `json:"impl"`
+	IO      string  `json:"io,omitempty"` // file 项的读取策略
+	Size    int     `json:"size"`         // 每次写入或读取的字节数
+	Bytes   int64   `json:"bytes"`
+	Seconds float64 `json:"seconds"`
+	MBps    float64 `json:"mb_per_s"`
+}
+
+var (
+	benchWriteSizes = []int{64, 1 << 10, 8 << 10, 64 << 10, 1 << 20}
+	benchBufferKB   = []int{4, 64, 256, 1 << 10, 4 << 10, 8 << 10}
+)
+
+// benchLoop 反复调用 step（每次处理 n 字节）直到用时达到 d，返回总字节数与实际用时。
+func benchLoop(d time.Duration, n int, step func()) (int64, time.Duration) {
+	var total int64
+	start := time.Now()
+	for {
+		// 每 16 次才读一次时钟，减少计时本身的开销。
+		for i := 0; i < 16; i++ {
+			step()
+		}
+		total += int64(16 * n)
+		if el := time.Since(start); el >= d {
+			return total, el
+		}
+	}
+}
+
+func newBenchResult(name, impl string, size int, total int64, el time.Duration) benchResult {
+	return benchResult{Case: name, Impl: impl, Size: size, Bytes: total, Seconds: el.Seconds(),
+		MBps: float64(total) / el.Seconds() / (1 << 20)}
+}
+
+func benchBlock(impl string, compress sm3BlockFunc, d time.Duration) benchResult {
+	v := sm3IV
+	block := make([]byte, 64)
+	total, el := benchLoop(d, 64, func() { compress(&v, block) })
+	runtime.KeepAlive(v)
+	return newBenchResult("block", impl, 64, total, el)
+}
+
+func benchWrite(impl string, compress sm3BlockFunc, size int, d time.Duration) benchResult {
+	buf := make([]byte, size)
+	h := newSM3DigestWith(compress)
+	total, el := benchLoop(d, size, func() { h.Write(buf) })
+	h.finish()
+	return newBenchResult("write", impl, size, total, el)
+}
+
+// benchFile 按 opt 的读取策略反复用 computeSM3File 计算 path。文件刚写入，除 direct 外通常都在页缓存中，
+// 测得的是读取调用与计算的开销而非磁盘速度。
+func benchFile(path string, size int64, opt ioOptions, d time.Duration) (benchResult, error) {
+	var total int64
+	start := time.Now()
+	for time.Since(start) < d {
+		if _, err := computeSM3File(path, hashOptions{IO: opt}, nil); err != nil {
+			return benchResult{}, err
+		}
+		total += size
+	}
+	r := newBenchResult("file", "generic", opt.bufferSize(), total, time.Since(start))
+	r.IO = opt.Strategy.String()
+	return r, nil
+}
+
+// benchFileOptions 是文件测试的组合：plain 取各种缓冲区大小，其余策略用默认缓冲区。
+func benchFileOptions() []ioOptions {
+	var opts []ioOptions
+	for _, kb := range benchBufferKB {
+		opts = append(opts, ioOptions{Strategy: ioPlain, BufferKB: kb})
+	}
+	for _, s := range []ioStrategy{ioReadAhead, ioMmap, ioDirect} {
+		opts = append(opts, ioOptions{Strategy: s})
+	}
+	return opts
+}
+
+func writeBenchFile(dir string, size int64) (string, error) {
+	f, err := os.CreateTemp(dir, "sm3-bench-*")
+	if err != nil {
+		return "", err
+	}
+	chunk := make([]byte, 1<<20)
+	for i := range chunk {
+		chunk[i] = byte(i * 31)
+	}
+	for done := int64(0); done < size && err == nil; done += int64(len(chunk)) {
+		_, err = f.Write(chunk[:min(int64(len(chunk)), size-done)])
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("sm3hash bench", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, tr("flag.json"))
+	dur := flags.Duration("time", 500*time.Millisecond, tr("flag.benchTime"))
+	fileMB := flags.Int64("file-mb", 64, tr("flag.benchFileMB"))
+	dir := flags.String("dir", "", tr("flag.benchDir"))
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("bench.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || *dur <= 0 {
+		flags.Usage()
+		return 2
+	}
+	var results []benchResult
+	for _, impl := range sm3Impls {
+		results = append(results, benchBlock(impl.Name, impl.compress, *dur))
+		for _, size := range benchWriteSizes {
+			results = append(results, benchWrite(impl.Name, impl.compress, size, *dur))
+		}
+	}
+	if *fileMB > 0 {
+		path, err := writeBenchFile(*dir, *fileMB<<20)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return 1
+		}
+		defer os.Remove(path)
+		for _, opt := range benchFileOptions() {
+			r, err := benchFile(path, *fileMB<<20, opt, *dur)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+				return 1
+			}
+			results = append(results, r)
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(struct {
+			GOOS    string        `json:"goos"`
+			GOARCH  string        `json:"goarch"`
+			CPUs    int           `json:"cpus"`
+			Results []benchResult `json:"results"`
+		}{runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), results})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.writeReport", err))
+			return 1
+		}
+		return 0
+	}
+	fmt.Printf("%s/%s, %d CPU\n", runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "case\timpl\tio\tsize\tMB/s\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.1f\t\n", r.Case, r.Impl, r.IO, formatBytes(int64(r.Size)), r.MBps)
+	}
+	tw.Flush()
+	return 0
+}
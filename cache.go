@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 增量哈希缓存：以 路径+大小+修改时间+文件标识 为键保存 SM3 摘要，存为单个本地 JSON 文件。
+// 每条记录带最近使用时间；保存时淘汰超过 cacheMaxAge 未使用的记录，条目数超过 cacheMaxEntries 时
+// 再按最近使用时间淘汰最旧的。sm3hash cache prune 还可以清掉文件已删除或已改变的记录。
+
+const cacheVersion = 1
+
+const (
+	cacheMaxAge     = 90 * 24 * time.Hour
+	cacheMaxEntries = 200000
+	// 命中时距上次记录的使用时间超过 cacheTouchEvery 才更新，避免每次命中都重写整个缓存文件。
+	cacheTouchEvery = 24 * time.Hour
+)
+
+type cacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"` // UnixNano
+	FileID  string `json:"id,omitempty"`
+	Digest  string `json:"sm3"`
+	Used    int64  `json:"used,omitempty"` // 最近一次写入或命中的时间（UnixNano）
+}
+
+type cacheFile struct {
+	Version int                   `json:"version"`
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+type hashCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+func defaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "SM3Hash", "cache.json"), nil
+}
+
+// loadHashCache 读取缓存文件；文件不存在时返回空缓存，版本不符时丢弃旧内容。
+// 文件损坏时改名为 .bad 并返回空缓存和错误，调用方提示后照常使用，下次保存时写入新文件。
+func loadHashCache(path string) (*hashCache, error) {
+	c := &hashCache{path: path, entries: map[string]cacheEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cf cacheFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		os.Rename(path, path+".bad")
+		return c, fmt.Errorf("%s: %w", tr("err.cacheCorrupt", filepath.Base(path)), err)
+	}
+	if cf.Version == cacheVersion && cf.Entries != nil {
+		c.entries = cf.Entries
+	}
+	// 旧版本写入的记录没有使用时间，从本次读取开始计算。
+	now := time.Now().UnixNano()
+	for k, e := range c.entries {
+		if e.Used == 0 {
+			e.Used = now
+			c.entries[k] = e
+		}
+	}
+	return c, nil
+}
+
+func (c *hashCache) Lookup(key string, want cacheEntry) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.Size != want.Size || e.ModTime != want.ModTime || e.FileID != want.FileID {
+		return "", false
+	}
+	if now := time.Now().UnixNano(); time.Duration(now-e.Used) > cacheTouchEvery {
+		e.Used = now
+		c.entries[key] = e
+		c.dirty = true
+	}
+	return e.Digest, true
+}
+
+func (c *hashCache) Store(key string, e cacheEntry) {
+	c.mu.Lock()
+	e.Used = time.Now().UnixNano()
+	c.entries[key] = e
+	c.dirty = true
+	c.mu.Unlock()
+}
+
+// Save 先写临时文件再改名，避免中途退出留下损坏的缓存。
+func (c *hashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	c.pruneLocked(time.Now(), cacheMaxAge, cacheMaxEntries, nil)
+	data, err := json.Marshal(cacheFile{Version: cacheVersion, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".cache-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Prune 淘汰超过 maxAge 未使用的记录和 keep 返回 false 的记录，再按最近使用时间只保留 maxEntries 条；
+// maxAge、maxEntries 为 0 表示不限，keep 为 nil 表示不检查。返回删除的条数。
+func (c *hashCache) Prune(now time.Time, maxAge time.Duration, maxEntries int, keep func(key string, e cacheEntry) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pruneLocked(now, maxAge, maxEntries, keep)
+}
+
+func (c *hashCache) pruneLocked(now time.Time, maxAge time.Duration, maxEntries int, keep func(key string, e cacheEntry) bool) int {
+	removed := 0
+	for k, e := range c.entries {
+		if (maxAge > 0 && now.Sub(time.Unix(0, e.Used)) > maxAge) || (keep != nil && !keep(k, e)) {
+			delete(c.entries, k)
+			removed++
+		}
+	}
+	if maxEntries > 0 && len(c.entries) > maxEntries {
+		keys := make([]string, 0, len(c.entries))
+		for k := range c.entries {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return c.entries[keys[i]].Used < c.entries[keys[j]].Used })
+		for _, k := range keys[:len(keys)-maxEntries] {
+			delete(c.entries, k)
+			removed++
+		}
+	}
+	if removed > 0 {
+		c.dirty = true
+	}
+	return removed
+}
+
+func (c *hashCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// cacheEntryCurrent 报告记录对应的文件是否仍存在且大小、修改时间和文件标识未变。
+func cacheEntryCurrent(path string, e cacheEntry) bool {
+	st, id, err := fileIdentity(path)
+	return err == nil && st.Size() == e.Size && st.ModTime().UnixNano() == e.ModTime && id == e.FileID
+}
+
+// runCache 实现 "sm3hash cache prune"。
+func runCache(args []string) int {
+	if len(args) == 0 || args[0] != "prune" {
+		fmt.Fprintln(os.Stderr, tr("cache.usage"))
+		return 2
+	}
+	flags := flag.NewFlagSet("sm3hash cache prune", flag.ContinueOnError)
+	cachePath := flags.String("cache", "", tr("flag.cache"))
+	maxAge := flags.Duration("max-age", cacheMaxAge, tr("flag.cacheMaxAge"))
+	maxEntries := flags.Int("max-entries", cacheMaxEntries, tr("flag.cacheMaxEntries"))
+	keepStale := flags.Bool("keep-stale", false, tr("flag.cacheKeepStale"))
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("cache.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	c, err := openCLICache(*cachePath)
+	if err != nil && c != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.cacheUnavailable", err))
+		return 1
+	}
+	var keep func(string, cacheEntry) bool
+	if !*keepStale {
+		keep = cacheEntryCurrent
+	}
+	removed := c.Prune(time.Now(), *maxAge, *maxEntries, keep)
+	if err := c.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.cacheSaveFailed", err))
+		return 1
+	}
+	fmt.Println(tr("msg.cachePruned", removed, c.Len()))
+	return 0
+}
+
+// cacheMode 控制一次计算如何使用缓存。
+type cacheMode struct {
+	cache   *hashCache // 为 nil 时不使用缓存
+	refresh bool       // 忽略已有记录，重新计算并更新
+}
+
+// cachedSM3File 先按文件元数据查缓存，未命中再调用 computeSM3File 并写回。
+func cachedSM3File(path string, opt hashOptions, cm cacheMode, progress progressFunc) (digest string, hit bool, err error) {
+	if cm.cache == nil || isArchiveMember(path) {
+		digest, err = computeSM3File(path, opt, progress)
+		return digest, false, err
+	}
+	info, id, err := fileIdentity(path)
+	if err != nil {
+		return "", false, err
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return "", false, err
+	}
+	want := cacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), FileID: id}
+	if !cm.refresh {
+		if d, ok := cm.cache.Lookup(key, want); ok {
+			if progress != nil {
+				progress(want.Size, want.Size)
+			}
+			return d, true, nil
+		}
+	}
+	digest, err = computeSM3File(path, opt, progress)
+	if err != nil {
+		return "", false, err
+	}
+	// 计算前后元数据一致才写入，避免把新内容的摘要记在旧元数据下。
+	if info, id, err := fileIdentity(path); err == nil &&
+		info.Size() == want.Size && info.ModTime().UnixNano() == want.ModTime && id == want.FileID {
+		want.Digest = digest
+		cm.cache.Store(key, want)
+	}
+	return digest, false, nil
+}
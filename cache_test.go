@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashCachePrune(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	c := &hashCache{entries: map[string]cacheEntry{
+		"old":    {Digest: "1", Used: now.Add(-100 * day).UnixNano()},
+		"recent": {Digest: "2", Used: now.Add(-1 * day).UnixNano()},
+		"older":  {Digest: "3", Used: now.Add(-10 * day).UnixNano()},
+		"newest": {Digest: "4", Used: now.UnixNano()},
+	}}
+	if n := c.Prune(now, 90*day, 0, nil); n != 1 {
+		t.Fatalf("age prune removed %d, want 1", n)
+	}
+	if _, ok := c.entries["old"]; ok {
+		t.Error("entry unused for 100 days survived")
+	}
+	if n := c.Prune(now, 0, 2, nil); n != 1 {
+		t.Fatalf("size prune removed %d, want 1", n)
+	}
+	if _, ok := c.entries["older"]; ok {
+		t.Error("least recently used entry survived the size limit")
+	}
+	n := c.Prune(now, 0, 0, func(k string, e cacheEntry) bool { return k != "recent" })
+	if n != 1 || c.Len() != 1 || !c.dirty {
+		t.Errorf("keep prune removed %d, left %d, dirty %v", n, c.Len(), c.dirty)
+	}
+}
+
+func TestHashCacheSaveEvictsAndTracksUse(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data")
+	if err := os.WriteFile(file, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "cache.json")
+	c, err := loadHashCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := cacheMode{cache: c}
+	if _, hit, err := cachedSM3File(file, defaultHashOptions, cm, nil); err != nil || hit {
+		t.Fatalf("first hash: hit %v, err %v", hit, err)
+	}
+	key, _ := filepath.Abs(file)
+	c.entries["stale"] = cacheEntry{Digest: "x", Used: time.Now().Add(-2 * cacheMaxAge).UnixNano()}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = loadHashCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.entries["stale"]; ok {
+		t.Error("Save kept an entry older than cacheMaxAge")
+	}
+	e, ok := c.entries[key]
+	if !ok || e.Used == 0 {
+		t.Fatalf("entry for %s = %+v, %v", key, e, ok)
+	}
+	// 命中时只有距上次记录超过 cacheTouchEvery 才更新使用时间。
+	if _, hit, _ := cachedSM3File(file, defaultHashOptions, cacheMode{cache: c}, nil); !hit || c.dirty {
+		t.Errorf("fresh hit: hit %v, dirty %v", hit, c.dirty)
+	}
+	e.Used = time.Now().Add(-2 * cacheTouchEvery).UnixNano()
+	c.entries[key] = e
+	if _, hit, _ := cachedSM3File(file, defaultHashOptions, cacheMode{cache: c}, nil); !hit || !c.dirty {
+		t.Errorf("old hit: hit %v, dirty %v", hit, c.dirty)
+	}
+
+	// 文件删除后 cacheEntryCurrent 不再保留该记录。
+	os.Remove(file)
+	if n := c.Prune(time.Now(), 0, 0, cacheEntryCurrent); n != 1 {
+		t.Errorf("stale prune removed %d, want 1", n)
+	}
+}
+
+// TestLoadHashCacheCorrupt 损坏的缓存文件改名为 .bad，本次从空缓存开始并能正常保存。
+func TestLoadHashCacheCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "entries": {`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadHashCache(path)
+	if err == nil || c == nil || c.Len() != 0 {
+		t.Fatalf("loadHashCache(corrupt) = %v, %v; want empty cache and an error", c, err)
+	}
+	if _, err := os.Stat(path + ".bad"); err != nil {
+		t.Errorf("corrupt file not renamed: %v", err)
+	}
+	c.Store("k", cacheEntry{Digest: "d"})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := loadHashCache(path); err != nil || c.Len() != 1 {
+		t.Errorf("reload after save: %v entries, err %v", c.Len(), err)
+	}
+}
+
+// TestHashCacheReplacedFile 同名文件被大小、修改时间都相同的新文件替换后，文件标识不同，不能命中旧记录。
+func TestHashCacheReplacedFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data")
+	if err := os.WriteFile(file, []byte("old!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, id, err := fileIdentity(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" {
+		t.Skip("no file IDs on this platform")
+	}
+	c := &hashCache{entries: map[string]cacheEntry{}}
+	if _, _, err := cachedSM3File(file, defaultHashOptions, cacheMode{cache: c}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tmp := filepath.Join(dir, "data.new")
+	if err := os.WriteFile(tmp, []byte("new!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatal(err)
+	}
+	if _, newID, _ := fileIdentity(file); newID == id {
+		t.Skip("replacement reused the file ID")
+	}
+
+	key, _ := filepath.Abs(file)
+	if cacheEntryCurrent(key, c.entries[key]) {
+		t.Error("cacheEntryCurrent kept the entry of a replaced file")
+	}
+	got, hit, err := cachedSM3File(file, defaultHashOptions, cacheMode{cache: c}, nil)
+	if err != nil || hit || got != sm3OneShot([]byte("new!")) {
+		t.Errorf("after replace: %s, hit %v, err %v", got, hit, err)
+	}
+}
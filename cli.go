@@ -0,0 +1,456 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 命令行模式：sm3hash [选项] 路径...，或 sm3hash <子命令> ...（compare、dedupe、baseline、watch、serve、selftest、bench、cache）。
+// 结果按 GNU 格式 "摘要  路径" 写到 stdout，错误与进度行写到 stderr。
+
+func runCLI(args []string) int {
+	currentLang = detectLanguage()
+	if len(args) > 0 && args[0] == "selftest" {
+		return runSelfTestCommand(args[1:])
+	}
+	if err := checkSelfTest(); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return exitSelfTest
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "compare":
+			return runCompare(args[1:])
+		case "dedupe":
+			return runDedupe(args[1:])
+		case "baseline":
+			return runBaseline(args[1:])
+		case "watch":
+			return runWatch(args[1:])
+		case "serve":
+			return runServe(args[1:])
+		case "bench":
+			return runBench(args[1:])
+		case "cache":
+			return runCache(args[1:])
+		}
+	}
+	if v, ok := langFromArgs(args); ok {
+		l, err := parseLanguage(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return 2
+		}
+		currentLang = l
+	}
+	flags := flag.NewFlagSet("sm3hash", flag.ContinueOnError)
+	flags.String("lang", "auto", tr("flag.lang"))
+	upper := flags.Bool("upper", false, tr("flag.upper"))
+	expect := flags.String("expect", "", tr("flag.expect"))
+	formatName := flags.String("format", "hex", tr("flag.format"))
+	progress := flags.String("progress", "auto", tr("flag.progress"))
+	retries := flags.Int("retries", defaultHashOptions.Retries, tr("flag.retries"))
+	denyWrite := flags.Bool("deny-write", false, tr("flag.denyWrite"))
+	noCache := flags.Bool("no-cache", false, tr("flag.noCache"))
+	refresh := flags.Bool("refresh", false, tr("flag.refresh"))
+	cachePath := flags.String("cache", "", tr("flag.cache"))
+	writeAttr := flags.Bool("write-attr", false, tr("flag.writeAttr"))
+	verifyAttr := flags.Bool("verify-attr", false, tr("flag.verifyAttr"))
+	archives := flags.Bool("archives", false, tr("flag.archives"))
+	var inputs []textInput
+	addInput := func(kind textInputKind) func(string) error {
+		return func(v string) error {
+			inputs = append(inputs, textInput{kind: kind, value: v})
+			return nil
+		}
+	}
+	flags.Func("string", tr("flag.string"), addInput(inputString))
+	flags.Func("hex", tr("flag.hex"), addInput(inputHex))
+	flags.Func("base64", tr("flag.base64"), addInput(inputBase64))
+	encName := flags.String("encoding", "utf-8", tr("flag.encoding"))
+	newline := flags.String("newline", "keep", tr("flag.newline"))
+	trimNewline := flags.Bool("trim-newline", false, tr("flag.trimNewline"))
+	sidecar := flags.Bool("sidecar", false, tr("flag.sidecar"))
+	verifySidecar := flags.Bool("verify-sidecar", false, tr("flag.verifySidecar"))
+	sidecarFormat := flags.String("sidecar-format", "gnu", tr("flag.sidecarFormat"))
+	sidecarExt := flags.String("sidecar-ext", ".sm3", tr("flag.sidecarExt"))
+	metricsPath := flags.String("metrics", "", tr("flag.metrics"))
+	sidecarDir := flags.String("sidecar-dir", "", tr("flag.sidecarDir"))
+	requireSidecar := flags.Bool("require-sidecar", false, tr("flag.requireSidecar"))
+	var ioOpt ioOptions
+	ioOpt.addFlags(flags)
+	logCfg := runLogConfigFromEnv()
+	logCfg.addFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("cli.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	enc, err := parseTextEncoding(*encName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	format, err := parseDigestFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	if *expect != "" {
+		if _, err := parseDigest(*expect); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: -expect: %v\n", err)
+			return 2
+		}
+	}
+	nl, err := parseNewlineMode(*newline)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	if err := openRunLog(logCfg); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.runLogUnavailable", err))
+		return 2
+	}
+	defer closeRunLog()
+	var paths []string
+	readStdin := false
+	for _, a := range flags.Args() {
+		if a == "-" {
+			readStdin = true
+		} else {
+			paths = append(paths, a)
+		}
+	}
+	if len(paths) == 0 && len(inputs) == 0 && !readStdin {
+		if st, err := os.Stdin.Stat(); err != nil || st.Mode()&os.ModeCharDevice != 0 {
+			flags.Usage()
+			return 2
+		}
+		readStdin = true
+	}
+	if readStdin {
+		inputs = append(inputs, textInput{kind: inputStdin})
+	}
+	showProgress, err := progressEnabled(*progress, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	scFormat, err := parseChecksumFormat(*sidecarFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	layout := sidecarLayout{ext: *sidecarExt, mirror: *sidecarDir, roots: paths}
+	expOpt := expandOptions{archives: *archives}
+	if *sidecar || *verifySidecar {
+		expOpt.skipSuffixes = append(expOpt.skipSuffixes, *sidecarExt)
+	}
+	opt := hashOptions{Retries: *retries, DenyWrite: *denyWrite, IO: ioOpt}
+	var cm cacheMode
+	if !*noCache {
+		cm.refresh = *refresh
+		cm.cache, err = openCLICache(*cachePath)
+		if err != nil && cm.cache != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.cacheUnavailable", err))
+		}
+	}
+
+	sum := runSummary{}
+	for _, in := range inputs {
+		data, err := in.bytes(os.Stdin, enc, nl, *trimNewline)
+		if err != nil {
+			sum.add(err)
+			fmt.Fprintf(os.Stderr, "sm3hash: %s: %v\n", in.label(), err)
+			continue
+		}
+		digest := sm3Hex(data)
+		fmt.Printf("%s  %s\n", formatDigest(digest, format, *upper), in.label())
+		sum.add(checkExpected(*expect, digest, in.label(), func(format string, args ...any) {
+			fmt.Printf(format, args...)
+		}))
+	}
+
+	files, skipped := expandPathsWith(paths, expOpt)
+	for _, err := range skipped {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.skipped", classifyError(err), err))
+	}
+	var bp batchProgress
+	bp.Reset()
+	q := newJobQueue(nil)
+	bp.Add(pendingTotals(q.Add(files)))
+	hashMetrics.setQueue("cli", q.Pending)
+	hashMetrics.observeSkipped(len(skipped))
+	pl := newProgressLine(os.Stderr, &bp, showProgress)
+	pl.start()
+
+	// task 处理单个文件并输出结果，返回的错误计入失败。
+	task := func(e queueEntry, progress progressFunc) (string, bool, error) {
+		res, hit, err := cachedSM3File(e.Path, opt, cm, progress)
+		if err != nil {
+			pl.fail(err)
+			return "", false, err
+		}
+		if *writeAttr {
+			if err := writeAttrAfterHash(e.Path, res); err != nil {
+				pl.fail(err)
+				return res, hit, err
+			}
+		}
+		// 压缩包成员没有真实路径，不写校验文件。
+		if *sidecar && !isArchiveMember(e.Path) {
+			if err := writeSidecar(layout, scFormat, e.Path, res); err != nil {
+				pl.fail(err)
+				return res, hit, err
+			}
+		}
+		pl.printf(os.Stdout, "%s  %s\n", formatDigest(res, format, *upper), e.Path)
+		return res, hit, checkExpected(*expect, res, e.Path, func(format string, args ...any) {
+			pl.printf(os.Stdout, format, args...)
+		})
+	}
+	switch {
+	case *verifySidecar:
+		task = func(e queueEntry, progress progressFunc) (string, bool, error) {
+			var want string
+			err := errNoSidecar
+			if !isArchiveMember(e.Path) {
+				if want, err = readSidecar(layout, e.Path); errors.Is(err, fs.ErrNotExist) {
+					err = errNoSidecar
+				}
+			}
+			if err == errNoSidecar {
+				pl.printf(os.Stdout, "%s: MISSING\n", e.Path)
+				if *requireSidecar {
+					return "", false, errVerifyFailed
+				}
+				return "", false, err
+			}
+			if err != nil {
+				pl.fail(err)
+				return "", false, err
+			}
+			got, err := computeSM3File(e.Path, opt, progress)
+			if err != nil {
+				pl.fail(err)
+				return "", false, err
+			}
+			if got != want {
+				pl.printf(os.Stdout, "%s: FAILED\n", e.Path)
+				return got, false, errVerifyFailed
+			}
+			pl.printf(os.Stdout, "%s: OK\n", e.Path)
+			return got, false, nil
+		}
+	case *verifyAttr:
+		task = func(e queueEntry, progress progressFunc) (string, bool, error) {
+			c, err := verifyDigestAttr(e.Path, opt, progress)
+			if err != nil {
+				pl.fail(err)
+				return "", false, err
+			}
+			pl.printf(os.Stdout, "%s: %s\n", e.Path, c.Status)
+			if c.Status != attrOK {
+				return c.Actual, false, errVerifyFailed
+			}
+			return c.Actual, false, nil
+		}
+	}
+
+	sum.Skipped = len(skipped)
+	rl := runLogger("cli")
+	logSkipped(rl, skipped)
+	runStart := time.Now()
+	logRunStart(rl, len(files), bp.Snapshot().TotalBytes)
+	for {
+		e, ok := q.Next()
+		if !ok {
+			break
+		}
+		fp := fileProgress{batch: &bp}
+		start := time.Now()
+		digest, hit, err := task(e, fp.update)
+		fp.finish(e.Size)
+		hashMetrics.observeFile(e.Size, time.Since(start), hit, err)
+		logFileResult(rl, e.Path, e.Size, digest, time.Since(start), hit, err)
+		sum.add(err)
+		if hit {
+			sum.Cached++
+		}
+		q.Finish(e.ID, err)
+	}
+	pl.stop()
+	logRunFinish(rl, sum, time.Since(runStart))
+	closeArchives()
+	if cm.cache != nil {
+		if err := cm.cache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.cacheSaveFailed", err))
+		}
+	}
+	if *metricsPath != "" {
+		if err := writeMetricsFile(*metricsPath); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.metricsFailed", err))
+		}
+	}
+	if showProgress || sum.Failed > 0 || sum.Skipped > 0 || sum.Missing > 0 {
+		fmt.Fprintln(os.Stderr, sum)
+	}
+	if sum.Failed > 0 || sum.Skipped > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkExpected 在给出 -expect 时比较结果并输出 MATCH/MISMATCH，不一致时返回 errVerifyFailed。
+func checkExpected(expect, digest, name string, printf func(string, ...any)) error {
+	if expect == "" {
+		return nil
+	}
+	ok, err := matchDigest(expect, digest)
+	if err != nil {
+		return err
+	}
+	printf("%s: %s\n", name, matchLabel(ok))
+	if !ok {
+		return errVerifyFailed
+	}
+	return nil
+}
+
+// writeAttrAfterHash 写入属性时使用计算后的元数据；稳定性检查已保证与计算时一致。
+func writeAttrAfterHash(path, digest string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return writeDigestAttr(path, digest, info)
+}
+
+func openCLICache(path string) (*hashCache, error) {
+	if path == "" {
+		var err error
+		if path, err = defaultCachePath(); err != nil {
+			return nil, err
+		}
+	}
+	return loadHashCache(path)
+}
+
+func progressEnabled(mode string, w *os.File) (bool, error) {
+	switch mode {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	case "auto":
+		st, err := w.Stat()
+		return err == nil && st.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, errors.New(tr("msg.badProgress", mode))
+}
+
+// progressLine 在 stderr 上原地刷新一行总进度；输出结果前先擦除该行，避免与结果交错。
+type progressLine struct {
+	mu      sync.Mutex
+	w       io.Writer
+	batch   *batchProgress
+	enabled bool
+	width   int
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newProgressLine(w io.Writer, batch *batchProgress, enabled bool) *progressLine {
+	return &progressLine{w: w, batch: batch, enabled: enabled, done: make(chan struct{})}
+}
+
+func (p *progressLine) start() {
+	if !p.enabled {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		tick := time.NewTicker(200 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-tick.C:
+				p.draw()
+			}
+		}
+	}()
+}
+
+func (p *progressLine) stop() {
+	if !p.enabled {
+		return
+	}
+	close(p.done)
+	p.wg.Wait()
+	p.mu.Lock()
+	p.clear()
+	p.mu.Unlock()
+}
+
+func (p *progressLine) draw() {
+	snap := p.batch.Snapshot()
+	line := fmt.Sprintf("[%3d%%] %s", snap.Percent(), snap)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	width := displayWidth(line)
+	pad := p.width - width
+	if pad < 0 {
+		pad = 0
+	}
+	fmt.Fprintf(p.w, "\r%s%s", line, strings.Repeat(" ", pad))
+	p.width = width
+}
+
+func (p *progressLine) clear() {
+	if p.width > 0 {
+		fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", p.width))
+		p.width = 0
+	}
+}
+
+// displayWidth 粗略估算终端显示宽度，中日韩字符按两列计。
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// fail 输出单个文件的错误；校验不通过的结果已单独输出，不再重复。
+func (p *progressLine) fail(err error) {
+	if errors.Is(err, errVerifyFailed) {
+		return
+	}
+	p.printf(os.Stderr, "sm3hash: %s\n", tr("msg.errorKind", classifyError(err), err))
+}
+
+func (p *progressLine) printf(w io.Writer, format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fmt.Fprintf(w, format, args...)
+}
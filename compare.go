@@ -0,0 +1,297 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 目录比对：分别展开两个根目录，并行计算全部文件的 SM3，按相对路径比较内容，
+// 只在一侧出现但摘要相同的文件视为改名。
+
+type compareStatus int
+
+const (
+	cmpIdentical compareStatus = iota
+	cmpDiffer
+	cmpRenamed
+	cmpOnlyLeft
+	cmpOnlyRight
+	cmpError
+)
+
+var compareStatusNames = []string{"IDENTICAL", "DIFFER", "RENAMED", "ONLY-LEFT", "ONLY-RIGHT", "ERROR"}
+
+func (s compareStatus) String() string { return compareStatusNames[s] }
+
+type compareEntry struct {
+	Status compareStatus
+	Path   string // 相对路径；改名时为左侧路径
+	Other  string // 改名时的右侧路径，出错时为错误信息
+	Left   string // 左侧摘要
+	Right  string // 右侧摘要
+}
+
+type compareResult struct {
+	Entries []compareEntry
+	Counts  [cmpError + 1]int
+}
+
+// Equal 报告两棵树是否完全一致。
+func (r compareResult) Equal() bool {
+	return r.Counts[cmpIdentical] == len(r.Entries)
+}
+
+// hashJob 是比对中的一个待计算文件。
+type hashJob struct {
+	Path   string
+	Size   int64
+	Digest string
+	Err    error
+}
+
+// hashFilesParallel 用 workers 个协程计算 jobs 中的全部文件，结果写回各自的 Digest/Err。
+func hashFilesParallel(jobs []*hashJob, opt hashOptions, workers int, bp *batchProgress) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ch := make(chan *hashJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				fp := fileProgress{batch: bp}
+				j.Digest, j.Err = computeSM3File(j.Path, opt, fp.update)
+				fp.finish(j.Size)
+			}
+		}()
+	}
+	for _, j := range jobs {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// collectTree 展开 root 并返回 相对路径 → 任务。
+func collectTree(root string) (map[string]*hashJob, []error) {
+	files, skipped := expandPaths([]string{root})
+	out := make(map[string]*hashJob, len(files))
+	for _, f := range files {
+		rel, err := filepath.Rel(root, f)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		out[filepath.ToSlash(rel)] = &hashJob{Path: f, Size: pathSize(f)}
+	}
+	return out, skipped
+}
+
+func compareTrees(left, right string, opt hashOptions, workers int, bp *batchProgress) (compareResult, []error) {
+	l, lskip := collectTree(left)
+	r, rskip := collectTree(right)
+	skipped := append(lskip, rskip...)
+
+	var jobs []*hashJob
+	for _, m := range []map[string]*hashJob{l, r} {
+		for _, j := range m {
+			jobs = append(jobs, j)
+			bp.Add(1, j.Size)
+		}
+	}
+	hashFilesParallel(jobs, opt, workers, bp)
+	return matchTrees(l, r), skipped
+}
+
+// matchTrees 按相对路径比较两侧已计算的结果；同一路径两侧都出错时两个错误都记入报告。
+func matchTrees(l, r map[string]*hashJob) compareResult {
+	var res compareResult
+	add := func(e compareEntry) {
+		res.Entries = append(res.Entries, e)
+		res.Counts[e.Status]++
+	}
+	failed := func(rel string, sides ...*hashJob) bool {
+		var msgs []string
+		for _, j := range sides {
+			if j != nil && j.Err != nil {
+				msgs = append(msgs, j.Err.Error())
+			}
+		}
+		if len(msgs) > 0 {
+			add(compareEntry{Status: cmpError, Path: rel, Other: strings.Join(msgs, "; ")})
+		}
+		return len(msgs) > 0
+	}
+
+	var onlyLeft, onlyRight []string
+	for rel, lj := range l {
+		rj := r[rel]
+		if failed(rel, lj, rj) {
+			continue
+		}
+		switch {
+		case rj == nil:
+			onlyLeft = append(onlyLeft, rel)
+		case lj.Digest == rj.Digest:
+			add(compareEntry{Status: cmpIdentical, Path: rel, Left: lj.Digest, Right: rj.Digest})
+		default:
+			add(compareEntry{Status: cmpDiffer, Path: rel, Left: lj.Digest, Right: rj.Digest})
+		}
+	}
+	for rel, rj := range r {
+		if _, ok := l[rel]; !ok && !failed(rel, rj) {
+			onlyRight = append(onlyRight, rel)
+		}
+	}
+
+	// 改名：按摘要把只在左侧和只在右侧的文件一一配对。
+	sort.Strings(onlyLeft)
+	sort.Strings(onlyRight)
+	byDigest := map[string][]string{}
+	for _, rel := range onlyRight {
+		byDigest[r[rel].Digest] = append(byDigest[r[rel].Digest], rel)
+	}
+	renamed := map[string]bool{}
+	for _, rel := range onlyLeft {
+		d := l[rel].Digest
+		if cand := byDigest[d]; len(cand) > 0 {
+			byDigest[d] = cand[1:]
+			renamed[cand[0]] = true
+			add(compareEntry{Status: cmpRenamed, Path: rel, Other: cand[0], Left: d, Right: d})
+			continue
+		}
+		add(compareEntry{Status: cmpOnlyLeft, Path: rel, Left: d})
+	}
+	for _, rel := range onlyRight {
+		if !renamed[rel] {
+			add(compareEntry{Status: cmpOnlyRight, Path: rel, Right: r[rel].Digest})
+		}
+	}
+
+	sort.Slice(res.Entries, func(i, j int) bool {
+		a, b := res.Entries[i], res.Entries[j]
+		if a.Status != b.Status {
+			return compareRank(a.Status) < compareRank(b.Status)
+		}
+		return a.Path < b.Path
+	})
+	return res
+}
+
+// compareReport 把比对结果整理为报告；hideIdentical 时省略内容相同的文件。
+func compareReport(res compareResult, f digestFormat, upper, hideIdentical bool) report {
+	r := report{Columns: []string{"status", "path", "other", "left", "right"}}
+	enc := func(d string) string {
+		if d == "" {
+			return ""
+		}
+		return formatDigest(d, f, upper)
+	}
+	for _, e := range res.Entries {
+		if hideIdentical && e.Status == cmpIdentical {
+			continue
+		}
+		r.add(e.Status.String(), e.Path, e.Other, enc(e.Left), enc(e.Right))
+	}
+	for s, name := range compareStatusNames {
+		r.Summary = append(r.Summary, reportCount{Name: name, Count: int64(res.Counts[s])})
+	}
+	return r
+}
+
+// compareRank 决定报告中的顺序：错误与差异在前，相同的文件最后。
+func compareRank(s compareStatus) int {
+	if s == cmpIdentical {
+		return len(compareStatusNames)
+	}
+	return len(compareStatusNames) - 1 - int(s)
+}
+
+// runCompare 实现 "sm3hash compare 左目录 右目录"：完全一致时退出码为 0，有差异或错误时为 1。
+func runCompare(args []string) int {
+	flags := flag.NewFlagSet("sm3hash compare", flag.ContinueOnError)
+	upper := flags.Bool("upper", false, tr("flag.upper"))
+	formatName := flags.String("format", "hex", tr("flag.formatDisplay"))
+	reportName := flags.String("report", "text", tr("flag.report"))
+	output := flags.String("o", "", tr("flag.reportOutput"))
+	hideIdentical := flags.Bool("hide-identical", false, tr("flag.hideIdentical"))
+	workers := flags.Int("workers", runtime.NumCPU(), tr("flag.workers"))
+	progress := flags.String("progress", "auto", tr("flag.progress"))
+	retries := flags.Int("retries", defaultHashOptions.Retries, tr("flag.retries"))
+	var ioOpt ioOptions
+	ioOpt.addFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("compare.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	format, err := parseDigestFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	rf, err := parseReportFormat(*reportName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	showProgress, err := progressEnabled(*progress, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	left, right := flags.Arg(0), flags.Arg(1)
+	for _, root := range []string{left, right} {
+		if st, err := os.Stat(root); err != nil || !st.IsDir() {
+			if err == nil {
+				err = errors.New(tr("err.notDir", root))
+			}
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return 2
+		}
+	}
+
+	var bp batchProgress
+	bp.Reset()
+	pl := newProgressLine(os.Stderr, &bp, showProgress)
+	pl.start()
+	res, skipped := compareTrees(left, right, hashOptions{Retries: *retries, IO: ioOpt}, *workers, &bp)
+	pl.stop()
+	for _, err := range skipped {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.skipped", classifyError(err), err))
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeReport(w, rf, compareReport(res, format, *upper, *hideIdentical)); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.writeReport", err))
+		return 1
+	}
+	if !res.Equal() || len(skipped) > 0 {
+		return 1
+	}
+	return 0
+}
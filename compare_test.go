@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchTrees(t *testing.T) {
+	errL, errR := errors.New("left read failed"), errors.New("right read failed")
+	l := map[string]*hashJob{
+		"same":      {Digest: "01"},
+		"changed":   {Digest: "02"},
+		"old-name":  {Digest: "03"},
+		"left-only": {Digest: "04"},
+		"bad-left":  {Err: errL},
+		"bad-right": {Digest: "05"},
+		"bad-both":  {Err: errL},
+	}
+	r := map[string]*hashJob{
+		"same":       {Digest: "01"},
+		"changed":    {Digest: "12"},
+		"new-name":   {Digest: "03"},
+		"right-only": {Digest: "14"},
+		"bad-left":   {Digest: "06"},
+		"bad-right":  {Err: errR},
+		"bad-both":   {Err: errR},
+	}
+	res := matchTrees(l, r)
+	want := map[string]compareStatus{
+		"same": cmpIdentical, "changed": cmpDiffer, "old-name": cmpRenamed,
+		"left-only": cmpOnlyLeft, "right-only": cmpOnlyRight,
+		"bad-left": cmpError, "bad-right": cmpError, "bad-both": cmpError,
+	}
+	if len(res.Entries) != len(want) {
+		t.Fatalf("%d entries, want %d: %+v", len(res.Entries), len(want), res.Entries)
+	}
+	for _, e := range res.Entries {
+		if e.Status != want[e.Path] {
+			t.Errorf("%s: %v, want %v", e.Path, e.Status, want[e.Path])
+		}
+		switch e.Path {
+		case "old-name":
+			if e.Other != "new-name" {
+				t.Errorf("renamed to %q, want new-name", e.Other)
+			}
+		case "bad-left", "bad-right":
+			if strings.Count(e.Other, "read failed") != 1 {
+				t.Errorf("%s: error %q", e.Path, e.Other)
+			}
+		case "bad-both":
+			if !strings.Contains(e.Other, errL.Error()) || !strings.Contains(e.Other, errR.Error()) {
+				t.Errorf("both sides failed, reported %q", e.Other)
+			}
+		}
+	}
+	if res.Counts[cmpError] != 3 || res.Equal() {
+		t.Errorf("counts = %v, equal %v", res.Counts, res.Equal())
+	}
+}
+
+func TestCompareTrees(t *testing.T) {
+	left, right := t.TempDir(), t.TempDir()
+	for _, f := range []struct{ dir, name, data string }{
+		{left, "a", "same"}, {right, "a", "same"},
+		{left, "sub/b", "left"}, {right, "sub/b", "right"},
+	} {
+		p := filepath.Join(f.dir, filepath.FromSlash(f.name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(f.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var bp batchProgress
+	bp.Reset()
+	res, skipped := compareTrees(left, right, defaultHashOptions, 2, &bp)
+	if len(skipped) != 0 || res.Counts[cmpIdentical] != 1 || res.Counts[cmpDiffer] != 1 || len(res.Entries) != 2 {
+		t.Errorf("compareTrees = %+v, skipped %v", res, skipped)
+	}
+}
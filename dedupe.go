@@ -0,0 +1,367 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 重复文件查找：先按大小分组，再按首尾各 64 KiB 的部分摘要筛选，最后才对剩余候选计算完整 SM3。
+// 只报告重复组，可另外生成硬链接或删除脚本，本身不修改任何文件。
+
+const partialChunk = 64 * 1024
+
+type dupFile struct {
+	Path string
+	ID   string // 文件标识，指向同一文件的硬链接不算重复
+}
+
+type dupGroup struct {
+	Digest string
+	Size   int64
+	Files  []string // 按路径排序，第一个作为保留的文件
+}
+
+// Wasted 返回除保留文件外其余副本占用的字节数。
+func (g dupGroup) Wasted() int64 {
+	return g.Size * int64(len(g.Files)-1)
+}
+
+// partialSM3 计算文件开头和结尾各 partialChunk 字节的摘要；文件较小时即整个内容。
+func partialSM3(path string, size int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	d := newSM3Digest()
+	if size <= 2*partialChunk {
+		if _, err := io.Copy(d, f); err != nil {
+			return "", err
+		}
+		return sm3ToHex(d.finish()), nil
+	}
+	buf := make([]byte, partialChunk)
+	for _, off := range []int64{0, size - partialChunk} {
+		if _, err := f.ReadAt(buf, off); err != nil {
+			return "", err
+		}
+		d.Write(buf)
+	}
+	return sm3ToHex(d.finish()), nil
+}
+
+// candidateGroup 是一组可能重复的文件，key 为最近一轮筛选使用的摘要。
+type candidateGroup struct {
+	key   string
+	files []dupFile
+}
+
+// refineGroups 对每组候选计算 key，按 key 拆分并丢弃只剩一个文件的组。
+func refineGroups(groups []candidateGroup, workers int, key func(dupFile) (string, error)) (out []candidateGroup, errs []error) {
+	type item struct {
+		group int
+		file  dupFile
+		key   string
+		err   error
+	}
+	var items []*item
+	for gi, g := range groups {
+		for _, f := range g.files {
+			items = append(items, &item{group: gi, file: f})
+		}
+	}
+	ch := make(chan *item)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range ch {
+				it.key, it.err = key(it.file)
+			}
+		}()
+	}
+	for _, it := range items {
+		ch <- it
+	}
+	close(ch)
+	wg.Wait()
+
+	split := map[[2]string][]dupFile{}
+	var order [][2]string
+	for _, it := range items {
+		if it.err != nil {
+			errs = append(errs, it.err)
+			continue
+		}
+		k := [2]string{strconv.Itoa(it.group), it.key}
+		if _, ok := split[k]; !ok {
+			order = append(order, k)
+		}
+		split[k] = append(split[k], it.file)
+	}
+	for _, k := range order {
+		if len(split[k]) > 1 {
+			out = append(out, candidateGroup{key: k[1], files: split[k]})
+		}
+	}
+	return out, errs
+}
+
+// findDuplicates 在 paths 展开后的文件中查找内容相同的文件组，小于 minSize 的文件不参与。
+func findDuplicates(paths []string, minSize int64, opt hashOptions, workers int, bp *batchProgress) ([]dupGroup, []error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	files, skipped := expandPaths(paths)
+	bySize := map[int64][]dupFile{}
+	seenID := map[string]bool{}
+	for _, p := range files {
+		info, id, err := fileIdentity(p)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		if info.Size() < minSize {
+			continue
+		}
+		if id != "" {
+			if seenID[id] {
+				continue
+			}
+			seenID[id] = true
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], dupFile{Path: p, ID: id})
+	}
+	var groups []candidateGroup
+	for _, g := range bySize {
+		if len(g) > 1 {
+			groups = append(groups, candidateGroup{files: g})
+		}
+	}
+
+	sizeOf := func(f dupFile) int64 { return pathSize(f.Path) }
+	groups, errs := refineGroups(groups, workers, func(f dupFile) (string, error) {
+		return partialSM3(f.Path, sizeOf(f))
+	})
+	skipped = append(skipped, errs...)
+
+	for _, g := range groups {
+		for _, f := range g.files {
+			bp.Add(1, sizeOf(f))
+		}
+	}
+	groups, errs = refineGroups(groups, workers, func(f dupFile) (string, error) {
+		fp := fileProgress{batch: bp}
+		d, err := computeSM3File(f.Path, opt, fp.update)
+		fp.finish(sizeOf(f))
+		return d, err
+	})
+	skipped = append(skipped, errs...)
+
+	out := make([]dupGroup, 0, len(groups))
+	for _, g := range groups {
+		dg := dupGroup{Digest: g.key, Size: sizeOf(g.files[0])}
+		for _, f := range g.files {
+			dg.Files = append(dg.Files, f.Path)
+		}
+		sort.Strings(dg.Files)
+		out = append(out, dg)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Wasted() != out[j].Wasted() {
+			return out[i].Wasted() > out[j].Wasted()
+		}
+		return out[i].Files[0] < out[j].Files[0]
+	})
+	return out, skipped
+}
+
+// dedupeReport 每个重复文件一行，保留的文件标记为 KEEP，其余为 DUP。
+func dedupeReport(groups []dupGroup, f digestFormat, upper bool) report {
+	r := report{Columns: []string{"group", "action", "size", "digest", "path"}}
+	var files, wasted int64
+	for i, g := range groups {
+		digest := formatDigest(g.Digest, f, upper)
+		for j, p := range g.Files {
+			action := "DUP"
+			if j == 0 {
+				action = "KEEP"
+			}
+			r.add(strconv.Itoa(i+1), action, strconv.FormatInt(g.Size, 10), digest, p)
+		}
+		files += int64(len(g.Files) - 1)
+		wasted += g.Wasted()
+	}
+	r.Summary = []reportCount{{"groups", int64(len(groups))}, {"duplicates", files}, {"wasted_bytes", wasted}}
+	return r
+}
+
+type scriptAction int
+
+const (
+	scriptHardlink scriptAction = iota
+	scriptRemove
+)
+
+// writeDedupeScript 生成处理重复文件的脚本（sh 或 Windows cmd），保留每组的第一个文件。
+func writeDedupeScript(w io.Writer, groups []dupGroup, action scriptAction, shell string) error {
+	var b strings.Builder
+	switch shell {
+	case "sh":
+		b.WriteString("#!/bin/sh\n# " + tr("dedupe.scriptNote") + "\nset -e\n")
+	case "cmd":
+		b.WriteString("@echo off\r\nrem " + tr("dedupe.scriptNote") + "\r\nchcp 65001 >nul\r\n")
+	default:
+		return errors.New(tr("err.scriptShell", shell))
+	}
+	for _, g := range groups {
+		keep := g.Files[0]
+		fmt.Fprintf(&b, scriptComment(shell), g.Digest, g.Size, keep)
+		for _, dup := range g.Files[1:] {
+			switch {
+			case shell == "sh" && action == scriptHardlink:
+				fmt.Fprintf(&b, "ln -f -- %s %s\n", shQuote(keep), shQuote(dup))
+			case shell == "sh":
+				fmt.Fprintf(&b, "rm -f -- %s\n", shQuote(dup))
+			case action == scriptHardlink:
+				fmt.Fprintf(&b, "del /f \"%s\" && mklink /H \"%s\" \"%s\" >nul\r\n", cmdPath(dup), cmdPath(dup), cmdPath(keep))
+			default:
+				fmt.Fprintf(&b, "del /f \"%s\"\r\n", cmdPath(dup))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// scriptComment 返回每组开头的注释格式（摘要、大小、保留的文件）。
+func scriptComment(shell string) string {
+	if shell == "cmd" {
+		return "rem " + tr("dedupe.scriptGroup") + "\r\n"
+	}
+	return "# " + tr("dedupe.scriptGroup") + "\n"
+}
+
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func cmdPath(s string) string {
+	return strings.ReplaceAll(filepath.FromSlash(s), "%", "%%")
+}
+
+// runDedupe 实现 "sm3hash dedupe 目录..."：找到重复文件时退出码为 1。
+func runDedupe(args []string) int {
+	flags := flag.NewFlagSet("sm3hash dedupe", flag.ContinueOnError)
+	upper := flags.Bool("upper", false, tr("flag.upper"))
+	formatName := flags.String("format", "hex", tr("flag.formatDisplay"))
+	reportName := flags.String("report", "text", tr("flag.report"))
+	output := flags.String("o", "", tr("flag.reportOutput"))
+	minSize := flags.Int64("min-size", 1, tr("flag.minSize"))
+	workers := flags.Int("workers", runtime.NumCPU(), tr("flag.workers"))
+	opt := defaultHashOptions
+	opt.IO.addFlags(flags)
+	progress := flags.String("progress", "auto", tr("flag.progress"))
+	script := flags.String("script", "", tr("flag.script"))
+	scriptShell := flags.String("script-shell", defaultScriptShell(), tr("flag.scriptShell"))
+	scriptMode := flags.String("script-action", "hardlink", tr("flag.scriptAction"))
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("dedupe.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	format, err := parseDigestFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	rf, err := parseReportFormat(*reportName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	showProgress, err := progressEnabled(*progress, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	var action scriptAction
+	switch *scriptMode {
+	case "hardlink":
+		action = scriptHardlink
+	case "remove":
+		action = scriptRemove
+	default:
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.scriptAction", *scriptMode))
+		return 2
+	}
+	if *scriptShell != "sh" && *scriptShell != "cmd" {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.scriptShell", *scriptShell))
+		return 2
+	}
+
+	var bp batchProgress
+	bp.Reset()
+	pl := newProgressLine(os.Stderr, &bp, showProgress)
+	pl.start()
+	groups, skipped := findDuplicates(flags.Args(), *minSize, opt, *workers, &bp)
+	pl.stop()
+	for _, err := range skipped {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.skipped", classifyError(err), err))
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeReport(w, rf, dedupeReport(groups, format, *upper)); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.writeReport", err))
+		return 1
+	}
+	if *script != "" {
+		f, err := os.Create(*script)
+		if err == nil {
+			err = writeDedupeScript(f, groups, action, *scriptShell)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.writeScript", err))
+			return 1
+		}
+	}
+	if len(groups) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func defaultScriptShell() string {
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	return "sh"
+}
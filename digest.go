@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// 摘要的显示编码。内部统一以小写十六进制传递，只在输出时转换；校验时接受任意一种编码。
+
+type digestFormat int
+
+const (
+	digestHex digestFormat = iota
+	digestBase64
+	digestBase64URL
+	digestBase32
+	digestSRI       // 子资源完整性风格 "sm3-<base64>"
+	digestMultihash // multihash 前缀（SM3 代码 0x534d、长度 32）加摘要，以十六进制显示
+)
+
+var digestFormats = []struct {
+	name  string // 命令行取值
+	label string // 界面显示用的消息键
+}{
+	{"hex", "fmt.hex"},
+	{"base64", "fmt.base64"},
+	{"base64url", "fmt.base64url"},
+	{"base32", "fmt.base32"},
+	{"sri", "fmt.sri"},
+	{"multihash", "fmt.multihash"},
+}
+
+// multihashPrefix 为 varint(0x534d) 与摘要长度 0x20。
+var multihashPrefix = []byte{0xcd, 0xa6, 0x01, 0x20}
+
+func (f digestFormat) String() string { return digestFormats[f].name }
+
+func parseDigestFormat(s string) (digestFormat, error) {
+	norm := strings.ReplaceAll(strings.ToLower(s), "-", "")
+	for i, df := range digestFormats {
+		if norm == df.name {
+			return digestFormat(i), nil
+		}
+	}
+	names := make([]string, len(digestFormats))
+	for i, df := range digestFormats {
+		names[i] = df.name
+	}
+	return 0, errors.New(tr("err.digestFormat", s, strings.Join(names, ", ")))
+}
+
+// formatDigest 把十六进制摘要转换为指定编码；upper 只影响十六进制形式（含 multihash）。
+func formatDigest(hexDigest string, f digestFormat, upper bool) string {
+	raw, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return hexDigest
+	}
+	var s string
+	switch f {
+	case digestBase64:
+		return base64.StdEncoding.EncodeToString(raw)
+	case digestBase64URL:
+		return base64.RawURLEncoding.EncodeToString(raw)
+	case digestBase32:
+		return base32.StdEncoding.EncodeToString(raw)
+	case digestSRI:
+		return "sm3-" + base64.StdEncoding.EncodeToString(raw)
+	case digestMultihash:
+		s = hex.EncodeToString(append(append([]byte{}, multihashPrefix...), raw...))
+	default:
+		s = strings.ToLower(hexDigest)
+	}
+	if upper {
+		s = strings.ToUpper(s)
+	}
+	return s
+}
+
+// parseDigest 识别十六进制（大小写均可）、Base64（标准或 URL 安全，可省略填充）、Base32、
+// "sm3-" 前缀的 SRI 以及 multihash 形式，返回小写十六进制摘要。
+func parseDigest(s string) (string, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if rest, ok := strings.CutPrefix(s, "sm3-"); ok {
+		s = rest
+	} else if len(s) > 3 && strings.EqualFold(s[:4], "sm3:") {
+		s = s[4:]
+	}
+	raw, ok := decodeDigestBytes(s)
+	if !ok {
+		return "", errors.New(tr("err.digestUnknown", s))
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func decodeDigestBytes(s string) ([]byte, bool) {
+	var candidates [][]byte
+	if b, err := hex.DecodeString(s); err == nil {
+		candidates = append(candidates, b)
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			candidates = append(candidates, b)
+		}
+	}
+	upper := strings.ToUpper(s)
+	for _, enc := range []*base32.Encoding{base32.StdEncoding, base32.StdEncoding.WithPadding(base32.NoPadding)} {
+		if b, err := enc.DecodeString(upper); err == nil {
+			candidates = append(candidates, b)
+		}
+	}
+	for _, b := range candidates {
+		if len(b) == len(multihashPrefix)+32 && string(b[:len(multihashPrefix)]) == string(multihashPrefix) {
+			b = b[len(multihashPrefix):]
+		}
+		if len(b) == 32 {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// matchDigest 以常量时间比较期望值与计算结果；expected 可以是任意支持的编码，actual 为十六进制。
+func matchDigest(expected, actual string) (bool, error) {
+	want, err := parseDigest(expected)
+	if err != nil {
+		return false, err
+	}
+	a, err1 := hex.DecodeString(want)
+	b, err2 := hex.DecodeString(actual)
+	if err1 != nil || err2 != nil {
+		return false, errors.New(tr("err.digestUnknown", actual))
+	}
+	return subtle.ConstantTimeCompare(a, b) == 1, nil
+}
+
+func matchLabel(ok bool) string {
+	if ok {
+		return "MATCH"
+	}
+	return "MISMATCH"
+}
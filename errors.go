@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// 单个文件的失败原因分类，批量运行时逐条记录，不再弹窗。
+
+type errKind int
+
+const (
+	errKindNone errKind = iota
+	errKindNotFound
+	errKindPermission
+	errKindIO
+	errKindChanged
+	errKindVerify
+	errKindCanceled
+)
+
+// errFileChanged 表示文件在读取过程中被修改。
+var errFileChanged error = localizedError("err.changed")
+
+// errCanceled 表示计算被用户或任务取消。
+var errCanceled error = localizedError("err.canceled")
+
+// errVerifyFailed 表示校验结果与预期不符（详细状态已单独输出）。
+var errVerifyFailed error = localizedError("err.verifyFailed")
+
+func (k errKind) String() string {
+	switch k {
+	case errKindNone:
+		return ""
+	case errKindNotFound:
+		return tr("kind.notFound")
+	case errKindPermission:
+		return tr("kind.permission")
+	case errKindChanged:
+		return tr("kind.changed")
+	case errKindVerify:
+		return tr("kind.verify")
+	case errKindCanceled:
+		return tr("kind.canceled")
+	}
+	return tr("kind.io")
+}
+
+func classifyError(err error) errKind {
+	switch {
+	case err == nil:
+		return errKindNone
+	case errors.Is(err, fs.ErrNotExist):
+		return errKindNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return errKindPermission
+	case errors.Is(err, errFileChanged):
+		return errKindChanged
+	case errors.Is(err, errVerifyFailed):
+		return errKindVerify
+	case errors.Is(err, errCanceled):
+		return errKindCanceled
+	}
+	return errKindIO
+}
+
+// runSummary 统计一次批量运行的结果。
+type runSummary struct {
+	OK      int
+	Cached  int // OK 中命中缓存的数量
+	Failed  int
+	Skipped int
+	Missing int // 校验时没有找到校验文件的数量，除非要求必须有校验文件，否则不算失败
+}
+
+func (s *runSummary) add(err error) {
+	if errors.Is(err, errNoSidecar) {
+		s.Missing++
+	} else if err != nil {
+		s.Failed++
+	} else {
+		s.OK++
+	}
+}
+
+func (s runSummary) String() string {
+	ok := tr("summary.ok", s.OK)
+	if s.Cached > 0 {
+		ok += tr("summary.cached", s.Cached)
+	}
+	rest := tr("summary.rest", ok, s.Failed, s.Skipped)
+	if s.Missing > 0 {
+		rest += tr("summary.missing", s.Missing)
+	}
+	return rest
+}
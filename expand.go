@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandOptions 控制目录展开时的过滤规则。
+type expandOptions struct {
+	skipSuffixes []string // 跳过以这些后缀结尾的文件（如校验文件 .sm3）
+	archives     bool     // 把 zip/tar/tar.gz/gz 当作目录展开为成员
+}
+
+func (o expandOptions) skip(path string) bool {
+	for _, suf := range o.skipSuffixes {
+		if suf != "" && strings.HasSuffix(strings.ToLower(path), strings.ToLower(suf)) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPaths 递归展开目录，无法访问的路径记入 skipped 而不中断。
+func expandPaths(paths []string) (files []string, skipped []error) {
+	return expandPathsWith(paths, expandOptions{})
+}
+
+func expandPathsWith(paths []string, opt expandOptions) (files []string, skipped []error) {
+	out := []string{}
+	seen := map[string]struct{}{}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil && isArchiveMember(p) {
+			if _, ok := seen[p]; !ok {
+				seen[p] = struct{}{}
+				out = append(out, p)
+			}
+			continue
+		}
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		if info.IsDir() {
+			filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					skipped = append(skipped, err)
+					return nil
+				}
+				if d.IsDir() || opt.skip(path) {
+					return nil
+				}
+				// 与 WalkDir 一样不进入指向目录的符号链接，避免循环和重复计算。
+				if d.Type()&fs.ModeSymlink != 0 {
+					if st, err := os.Stat(path); err == nil && st.IsDir() {
+						return nil
+					}
+				}
+				if _, ok := seen[path]; ok {
+					return nil
+				}
+				seen[path] = struct{}{}
+				out, skipped = opt.add(out, skipped, path)
+				return nil
+			})
+		} else {
+			if _, ok := seen[p]; ok || opt.skip(p) {
+				continue
+			}
+			seen[p] = struct{}{}
+			out, skipped = opt.add(out, skipped, p)
+		}
+	}
+	return out, skipped
+}
+
+// add 加入一个文件；开启 archives 时压缩包替换为其成员的虚拟路径。
+func (o expandOptions) add(out []string, skipped []error, p string) ([]string, []error) {
+	if !o.archives || archiveKindOf(p) == archiveNone {
+		return append(out, p), skipped
+	}
+	members, err := listArchive(p)
+	if err != nil {
+		return out, append(skipped, err)
+	}
+	for _, m := range members {
+		out = append(out, p+archiveSep+m.Name)
+	}
+	return out, skipped
+}
+
+// pathSize 返回文件或压缩包成员的大小，未知时为 0。
+func pathSize(p string) int64 {
+	if size, ok := archiveMemberSize(p); ok {
+		return size
+	}
+	if st, err := os.Stat(p); err == nil {
+		return st.Size()
+	}
+	return 0
+}
+
+// relativeToRoots 返回 file 相对于所属输入路径的位置；输入本身是文件时返回文件名。
+func relativeToRoots(file string, roots []string) string {
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		if filepath.Clean(file) == root {
+			return filepath.Base(file)
+		}
+		if rel, err := filepath.Rel(root, file); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel
+		}
+	}
+	return filepath.Base(file)
+}
@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package main
+
+import (
+	"io/fs"
+	"os"
+)
+
+func fileIdentity(path string) (fs.FileInfo, string, error) {
+	info, err := os.Stat(path)
+	return info, "", err
+}
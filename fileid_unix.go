@@ -0,0 +1,23 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// fileIdentity 返回文件信息及设备号+inode 组成的标识。
+func fileIdentity(path string) (fs.FileInfo, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	id := ""
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		id = fmt.Sprintf("%x:%x", uint64(st.Dev), uint64(st.Ino))
+	}
+	return info, id, nil
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// fileIdentity 返回文件信息及卷序列号+文件索引组成的标识；无法打开句柄时标识为空。
+func fileIdentity(path string) (fs.FileInfo, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return info, "", nil
+	}
+	defer f.Close()
+	var d syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(f.Fd()), &d); err != nil {
+		return info, "", nil
+	}
+	return info, fmt.Sprintf("%x:%x%08x", d.VolumeSerialNumber, d.FileIndexHigh, d.FileIndexLow), nil
+}
@@ -1,3 +1,5 @@
 module github.com/sfjdr/SM3Hash
 
 go 1.21
+
+require golang.org/x/text v0.22.0
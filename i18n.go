@@ -0,0 +1,372 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 界面与命令行文字的消息目录。每条消息在同一处给出全部语言的文本，新增语言时在 message 中加字段，
+// i18n_test.go 会指出缺失的翻译、格式占位符不一致的条目和代码中引用了却不存在的键。
+
+type language int
+
+const (
+	langZH language = iota
+	langEN
+)
+
+// languageNames 是 -lang 与设置文件中使用的语言代码，顺序与 language 一致。
+var languageNames = []string{"zh", "en"}
+
+type message struct {
+	zh string
+	en string
+}
+
+func (m message) text(l language) string {
+	if l == langEN {
+		return m.en
+	}
+	return m.zh
+}
+
+var currentLang = langZH
+
+var catalog = map[string]message{
+	// 窗口与控件
+	"app.title":        {"SM3校验工具 v1.0 (Go)", "SM3 Hash Tool v1.0 (Go)"},
+	"dlg.notice":       {"提示", "Notice"},
+	"grp.display":      {"显示选项", "Display"},
+	"chk.size":         {"文件大小", "File size"},
+	"chk.time":         {"计算时间", "Time"},
+	"chk.upper":        {"结果大写", "Uppercase"},
+	"chk.lock":         {"锁定读取", "Lock file"},
+	"chk.cache":        {"使用缓存", "Use cache"},
+	"grp.algo":         {"哈希算法", "Algorithm"},
+	"lbl.sm3":          {"SM3 (国密)", "SM3 (GM/T 0004)"},
+	"lbl.format":       {"输出编码", "Encoding"},
+	"lbl.lang":         {"语言", "Language"},
+	"lang.auto":        {"自动", "Auto"},
+	"lbl.progress":     {"进度", "File"},
+	"lbl.total":        {"总进度", "Total"},
+	"lbl.text":         {"文本", "Text"},
+	"lbl.expected":     {"期望值", "Expect"},
+	"btn.browse":       {"浏览...", "Browse..."},
+	"btn.clear":        {"清空", "Clear"},
+	"btn.copy":         {"复制", "Copy"},
+	"btn.save":         {"保存", "Save"},
+	"btn.watch":        {"监视...", "Watch..."},
+	"btn.stopWatch":    {"停止监视", "Stop watch"},
+	"btn.start":        {"开始", "Start"},
+	"btn.exit":         {"退出", "Exit"},
+	"btn.up":           {"上移", "Up"},
+	"btn.down":         {"下移", "Down"},
+	"btn.first":        {"优先", "First"},
+	"btn.remove":       {"移除", "Remove"},
+	"btn.retry":        {"重试失败", "Retry"},
+	"btn.hashText":     {"计算文本", "Hash text"},
+	"btn.paste":        {"粘贴", "Paste"},
+	"col.id":           {"编号", "ID"},
+	"col.status":       {"状态", "Status"},
+	"col.file":         {"文件", "File"},
+	"input.hex":        {"十六进制", "Hex"},
+	"dlg.open":         {"选择要校验的文件", "Select a file to hash"},
+	"dlg.save":         {"保存校验结果", "Save results"},
+	"dlg.watch":        {"选择要监视的目录", "Select a folder to watch"},
+	"filter.all":       {"所有文件 (*.*)", "All files (*.*)"},
+	"filter.text":      {"文本文件 (*.txt)", "Text files (*.txt)"},
+	"fmt.hex":          {"十六进制", "Hex"},
+	"fmt.base64":       {"Base64", "Base64"},
+	"fmt.base64url":    {"Base64 URL", "Base64 URL"},
+	"fmt.base32":       {"Base32", "Base32"},
+	"fmt.sri":          {"SRI", "SRI"},
+	"fmt.multihash":    {"Multihash", "Multihash"},
+	"state.pending":    {"等待", "Pending"},
+	"state.running":    {"计算中", "Hashing"},
+	"state.done":       {"完成", "Done"},
+	"state.failed":     {"失败", "Failed"},
+	"state.unknown":    {"未知", "Unknown"},
+	"kind.notFound":    {"文件不存在", "not found"},
+	"kind.permission":  {"权限不足", "permission denied"},
+	"kind.io":          {"读取错误", "read error"},
+	"kind.changed":     {"文件不稳定", "file unstable"},
+	"kind.verify":      {"校验未通过", "verification failed"},
+	"kind.canceled":    {"已取消", "canceled"},
+	"err.changed":      {"读取期间文件被修改", "file was modified while reading"},
+	"err.canceled":     {"已取消", "canceled"},
+	"err.verifyFailed": {"校验未通过", "verification failed"},
+	"summary.ok":       {"%d 成功", "%d ok"},
+	"summary.cached":   {" (%d 命中缓存)", " (%d cached)"},
+	"summary.rest":     {"%s, %d 失败, %d 跳过", "%s, %d failed, %d skipped"},
+	"summary.missing":  {", %d 缺少校验文件", ", %d without checksum file"},
+	"progress.line":    {"%d/%d 文件  %s/%s  %.1f MB/s  剩余 %s", "%d/%d files  %s/%s  %.1f MB/s  ETA %s"},
+
+	// 输出区域与提示
+	"msg.retry":              {"重试失败文件: %d 个", "Retrying failed files: %d"},
+	"msg.saveFailed":         {"保存失败: %v", "Save failed: %v"},
+	"msg.error":              {"错误: %v", "Error: %v"},
+	"msg.errorKind":          {"错误[%s]: %v", "Error [%s]: %v"},
+	"msg.textResult":         {"文本 (%s, %d 字节): %s", "Text (%s, %d bytes): %s"},
+	"msg.done":               {"完成。", "Done."},
+	"msg.expectedInvalid":    {"期望值无效: %v", "Invalid expected digest: %v"},
+	"msg.match":              {"比对: 一致 (MATCH)", "Compare: MATCH"},
+	"msg.mismatch":           {"比对: 不一致 (MISMATCH)", "Compare: MISMATCH"},
+	"msg.clipboardExpected":  {"已从剪贴板识别期望值: %s", "Expected digest taken from clipboard: %s"},
+	"msg.watchLogOpenFailed": {"无法打开监视日志: %v", "Cannot open watch log: %v"},
+	"msg.watchStart":         {"开始监视: %s（结果日志 %s）", "Watching %s (results log %s)"},
+	"msg.watchStop":          {"已停止监视", "Stopped watching"},
+	"msg.watchLogFailed":     {"写入监视日志失败: %v", "Failed to write watch log: %v"},
+	"msg.skipped":            {"跳过[%s]: %v", "Skipped [%s]: %v"},
+	"msg.enqueued":           {"加入任务: %d 个文件", "Queued: %d files"},
+	"msg.internalError":      {"内部错误: %v", "Internal error: %v"},
+	"msg.cacheSaveFailed":    {"保存缓存失败: %v", "Failed to save cache: %v"},
+	"msg.cacheUnavailable":   {"缓存不可用: %v", "Cache unavailable: %v"},
+	"msg.runFinished":        {"本次结束: %s", "Run finished: %s"},
+	"msg.hashing":            {"开始计算: %s", "Hashing: %s"},
+	"msg.cached":             {" (缓存)", " (cached)"},
+	"msg.fileSize":           {"文件大小: %d 字节", "Size: %d bytes"},
+	"msg.elapsed":            {"耗时: %.2f s", "Time: %.2f s"},
+	"msg.settingsRead":       {"读取设置: %v", "Settings: %v"},
+	"msg.settingsSaveFailed": {"保存设置失败: %v", "Failed to save settings: %v"},
+	"msg.runLogUnavailable":  {"运行日志不可用: %v", "Run log unavailable: %v"},
+	"msg.metricsFailed":      {"写入指标失败: %v", "Failed to write metrics: %v"},
+	"msg.badProgress":        {"未知的 -progress 取值: %q", "unknown -progress value: %q"},
+	"msg.badLanguage":        {"未知的语言: %q（可选 auto、zh、en）", "unknown language: %q (choose auto, zh, en)"},
+
+	// 命令行
+	"cli.usage":            {"用法: sm3hash [选项] 文件或目录...（路径为 - 时读取标准输入）", "Usage: sm3hash [options] file-or-dir...  (use - to read standard input)"},
+	"flag.lang":            {"界面与消息语言: auto（按系统区域）、zh、en", "Language for messages: auto (from system locale), zh, en"},
+	"flag.upper":           {"摘要使用大写", "Print digests in uppercase"},
+	"flag.expect":          {"期望的摘要（十六进制或其他支持的编码），逐个比较并输出 MATCH/MISMATCH", "Expected digest (hex or another supported encoding); prints MATCH/MISMATCH for each input"},
+	"flag.format":          {"摘要的显示编码: hex、base64、base64url、base32、sri（sm3-<base64>）、multihash；校验文件始终为十六进制", "Digest display encoding: hex, base64, base64url, base32, sri (sm3-<base64>), multihash; checksum files always use hex"},
+	"flag.progress":        {"总进度显示: auto（stderr 为终端时显示）、on、off", "Overall progress line: auto (when stderr is a terminal), on, off"},
+	"flag.retries":         {"文件读取期间被修改时的重试次数", "Retries when a file changes while being read"},
+	"flag.denyWrite":       {"读取期间禁止其他进程写入（仅 Windows）", "Deny writes by other processes while reading (Windows only)"},
+	"flag.noCache":         {"不使用增量哈希缓存", "Do not use the incremental hash cache"},
+	"flag.cacheMaxAge":     {"删除超过该时长未使用的记录（0 表示不限）", "Remove entries unused for longer than this (0 = no limit)"},
+	"flag.cacheMaxEntries": {"最多保留的记录数，超出时删除最久未使用的（0 表示不限）", "Keep at most this many entries, dropping the least recently used (0 = no limit)"},
+	"flag.cacheKeepStale":  {"保留文件已删除或大小、修改时间已变的记录", "Keep entries whose file is gone or has a different size or mtime"},
+	"cache.usage":          {"用法: sm3hash cache prune [选项]", "Usage: sm3hash cache prune [options]"},
+	"msg.cachePruned":      {"已删除 %d 条缓存记录，保留 %d 条", "Removed %d cache entries, %d kept"},
+	"flag.refresh":         {"忽略缓存中的记录，重新计算并更新缓存", "Ignore cached entries, rehash and update the cache"},
+	"flag.cache":           {"缓存文件路径（默认位于用户缓存目录）", "Cache file path (default: in the user cache directory)"},
+	"flag.writeAttr":       {"计算后把摘要写入文件属性（Linux 扩展属性 user.sm3 / NTFS 数据流 :sm3）", "Store the digest in a file attribute after hashing (xattr user.sm3 on Linux / NTFS stream :sm3)"},
+	"flag.verifyAttr":      {"重新计算并与文件属性中保存的摘要比较", "Rehash and compare with the digest stored in the file attribute"},
+	"flag.archives":        {"把 .zip、.tar、.tar.gz/.tgz、.gz 当作目录，逐个计算其中的文件（archive.zip!/inner/path）", "Treat .zip, .tar, .tar.gz/.tgz and .gz as directories and hash their members (archive.zip!/inner/path)"},
+	"flag.string":          {"计算文本的摘要（可重复）", "Hash a text string (repeatable)"},
+	"flag.hex":             {"计算十六进制数据的摘要（可重复）", "Hash hex-encoded data (repeatable)"},
+	"flag.base64":          {"计算 Base64 数据的摘要（可重复）", "Hash Base64-encoded data (repeatable)"},
+	"flag.encoding":        {"文本编码: utf-8、gb18030、utf-16le", "Text encoding: utf-8, gb18030, utf-16le"},
+	"flag.newline":         {"文本换行: keep、lf、crlf", "Text newlines: keep, lf, crlf"},
+	"flag.trimNewline":     {"去掉文本末尾的一个换行", "Remove one trailing newline from text"},
+	"flag.sidecar":         {"为每个文件写一个校验文件（如 file.iso.sm3）", "Write a checksum file next to each file (e.g. file.iso.sm3)"},
+	"flag.verifySidecar":   {"查找每个文件的校验文件并核对摘要", "Find each file's checksum file and verify the digest"},
+	"flag.sidecarFormat":   {"校验文件格式: gnu（摘要  文件名）或 bsd（SM3 (文件名) = 摘要）", "Checksum file format: gnu (digest  name) or bsd (SM3 (name) = digest)"},
+	"flag.sidecarExt":      {"校验文件扩展名", "Checksum file extension"},
+	"flag.sidecarDir":      {"校验文件写入/查找的镜像目录，按输入路径的相对结构存放（默认与原文件同目录）", "Mirror directory for checksum files, laid out like the input paths (default: next to each file)"},
+	"flag.metrics":         {"结束时把 Prometheus 格式的指标写入此文件（供 node_exporter textfile 收集器）", "Write Prometheus metrics to this file when done (for the node_exporter textfile collector)"},
+	"flag.io":              {"文件读取策略: plain、readahead（读取与计算重叠）、mmap（仅 Linux）、direct（绕过页缓存），默认 plain", "File read strategy: plain, readahead (overlap reads with hashing), mmap (Linux only), direct (bypass the page cache); default plain"},
+	"flag.bufferKB":        {"读取缓冲区大小（KB，4～65536）", "Read buffer size in KB (4-65536)"},
+	"flag.logFile":         {"运行日志文件（默认取 SM3HASH_LOG_FILE，为空时不记录）", "Run log file (default: SM3HASH_LOG_FILE; empty disables logging)"},
+	"flag.logFormat":       {"运行日志格式: text 或 json", "Run log format: text or json"},
+	"flag.logLevel":        {"运行日志级别: debug、info、warn、error", "Run log level: debug, info, warn, error"},
+	"flag.logMaxMB":        {"运行日志超过该大小（MB）时滚动", "Rotate the run log when it exceeds this size (MB)"},
+	"flag.logKeep":         {"保留的旧运行日志个数", "Number of rotated run logs to keep"},
+	"flag.baselineKey":     {"HMAC-SM3 封印密钥文件，用于发现基线文件被篡改；不指定时只写 SM3 校验和，只能发现意外损坏", "HMAC-SM3 seal key file, needed to detect tampering with the baseline; without it only an SM3 checksum is written, which catches accidental corruption only"},
+	"msg.baselineUnkeyed":  {"基线没有密钥封印，只核对了 SM3 校验和，无法发现有意篡改（用 -key-file 创建基线以检测篡改）", "baseline has no keyed seal; only its SM3 checksum was verified, which cannot detect deliberate tampering (create it with -key-file)"},
+	"err.baselineSeal":     {"基线文件 HMAC 封印不符，已被篡改或密钥不同", "baseline HMAC seal does not match: the file was tampered with or the key differs"},
+	"err.baselineChecksum": {"基线文件校验和不符，文件已损坏或被改动", "baseline checksum does not match: the file is corrupt or was edited"},
+	"err.baselineUnsealed": {"%s: 提供了密钥但基线只有 SM3 校验和，可能已被替换", "%s: a key was given but the baseline only has an SM3 checksum; it may have been replaced"},
+	"serve.usage":          {"用法: sm3hash serve [选项]", "Usage: sm3hash serve [options]"},
+	"serve.listening":      {"SM3 服务监听 http://%s/v1/，按 Ctrl+C 停止", "SM3 service listening on http://%s/v1/, press Ctrl+C to stop"},
+	"flag.addr":            {"监听地址", "Listen address"},
+	"flag.maxBodyMB":       {"单个请求体的最大大小（MB）", "Maximum request body size (MB)"},
+	"flag.readTimeout":     {"读取整个请求的超时", "Timeout for reading a whole request"},
+	"flag.writeTimeout":    {"写响应的超时", "Timeout for writing a response"},
+	"flag.idleTimeout":     {"空闲连接的超时", "Timeout for idle connections"},
+	"flag.allowRoot":       {"允许路径任务读取的目录（可重复）", "Directory that path jobs may read (repeatable)"},
+	"flag.maxJobs":         {"同时运行的路径任务数", "Number of path jobs run at the same time"},
+	"err.allowRoot":        {"-allow-root %s 不是目录", "-allow-root %s is not a directory"},
+	"err.runLog":           {"运行日志: %v", "run log: %v"},
+	"api.methodPost":       {"只接受 POST", "only POST is allowed"},
+	"api.badKey":           {"缺少或无效的 HMAC 密钥（十六进制，放在 X-HMAC-Key 头）", "missing or invalid HMAC key (hex, in the X-HMAC-Key header)"},
+	"api.keyInQuery":       {"HMAC 密钥不能放在 URL 中，请改用 X-HMAC-Key 头", "the HMAC key must not be in the URL; send it in the X-HMAC-Key header"},
+	"api.badExpected":      {"期望值无效: %q", "invalid expected value: %q"},
+	"api.bodyTooLarge":     {"请求体超过 %d 字节", "request body exceeds %d bytes"},
+	"api.badBody":          {"读取请求体失败: %v", "failed to read request body: %v"},
+	"err.outsideRoots":     {"不在允许的目录内", "outside the allowed directories"},
+	"api.jobsDisabled":     {"未配置允许的目录（-allow-root），路径任务已禁用", "no allowed directories configured (-allow-root); path jobs are disabled"},
+	"api.badRequest":       {"请求格式错误: %v", "malformed request: %v"},
+	"api.emptyPaths":       {"paths 不能为空", "paths must not be empty"},
+	"api.badAlgorithm":     {"不支持的算法 %q（可选 sm3）", "unsupported algorithm %q (choose sm3)"},
+	"api.badPattern":       {"通配符 %q 无效", "invalid pattern %q"},
+	"api.outsideRoots":     {"%s: 不在允许的目录内", "%s: outside the allowed directories"},
+	"api.methodGetPost":    {"只接受 GET 或 POST", "only GET or POST is allowed"},
+	"api.jobNotFound":      {"任务 %s 不存在", "job %s not found"},
+	"api.notFound":         {"未知的接口", "unknown endpoint"},
+	"api.internal":         {"内部错误: %v", "internal error: %v"},
+	"flag.json":            {"以 JSON 输出结果", "Print results as JSON"},
+	"selftest.usage":       {"用法: sm3hash selftest [选项]", "Usage: sm3hash selftest [options]"},
+	"flag.selfTestFull":    {"追加一致性检查：填充边界两侧的定长向量、百万个 a", "Add conformance checks: fixed-length vectors around padding boundaries, a million 'a'"},
+	"flag.selfTestLarge":   {"-full 时追加 4 GiB 生成数据的检查（耗时较长）", "With -full, also check 4 GiB of generated data (slow)"},
+	"bench.usage":          {"用法: sm3hash bench [选项]", "Usage: sm3hash bench [options]"},
+	"flag.benchTime":       {"每项测试的持续时间", "Duration of each measurement"},
+	"flag.benchFileMB":     {"文件读取测试使用的临时文件大小（MB），0 表示跳过", "Size of the temporary file for read tests (MB); 0 skips them"},
+	"flag.benchDir":        {"临时文件所在目录（默认系统临时目录），可用来测量指定磁盘", "Directory for the temporary file (default: system temp dir), to measure a specific disk"},
+	"flag.workers":         {"并行计算的文件数", "Number of files hashed in parallel"},
+	"flag.formatDisplay":   {"摘要编码: hex、base64、base64url、base32、sri、multihash", "Digest encoding: hex, base64, base64url, base32, sri, multihash"},
+	"flag.report":          {"报告格式: text、json、csv", "Report format: text, json, csv"},
+	"flag.reportOutput":    {"报告写入文件（默认 stdout）", "Write the report to this file (default: stdout)"},
+	"flag.hideIdentical":   {"报告中省略内容相同的文件", "Omit identical files from the report"},
+	"flag.minSize":         {"忽略小于该字节数的文件", "Ignore files smaller than this many bytes"},
+	"flag.script":          {"生成处理脚本到该文件（不会自动执行）", "Write a cleanup script to this file (it is not run)"},
+	"flag.scriptShell":     {"脚本类型: sh 或 cmd", "Script type: sh or cmd"},
+	"flag.scriptAction":    {"脚本对重复文件的处理: hardlink（替换为指向保留文件的硬链接）或 remove（删除）", "What the script does with duplicates: hardlink (replace with a hard link to the kept file) or remove (delete)"},
+	"flag.baselineOut":     {"create: 基线文件路径；check: 报告写入文件（默认 stdout）", "create: baseline file path; check: write the report to this file (default: stdout)"},
+	"flag.baselineFile":    {"check: 基线文件路径", "check: baseline file path"},
+	"flag.baselineNote":    {"create: 写入基线的确认人或说明", "create: reviewer or note recorded in the baseline"},
+	"flag.baselineReport":  {"check: 报告格式 text、json、csv", "check: report format text, json, csv"},
+	"flag.watchLog":        {"结果日志（JSON Lines）路径，默认位于用户缓存目录", "Results log (JSON Lines) path; default: in the user cache directory"},
+	"flag.watchLogMaxMB":   {"日志超过该大小（MB）时滚动", "Rotate the log when it exceeds this size (MB)"},
+	"flag.watchLogKeep":    {"保留的旧日志个数", "Number of rotated logs to keep"},
+	"flag.quiet":           {"文件最后一次变化后等待多久再计算", "How long to wait after a file's last change before hashing it"},
+	"flag.poll":            {"不使用系统通知，定期扫描目录（适用于网络共享）", "Scan directories periodically instead of using change notifications (for network shares)"},
+	"flag.pollInterval":    {"定期扫描的间隔", "Interval between scans"},
+	"flag.initial":         {"启动时先计算目录中已有的文件", "Hash existing files on startup"},
+	"compare.usage":        {"用法: sm3hash compare [选项] 左目录 右目录", "Usage: sm3hash compare [options] left-dir right-dir"},
+	"dedupe.usage":         {"用法: sm3hash dedupe [选项] 目录...", "Usage: sm3hash dedupe [options] dir..."},
+	"watch.usage":          {"用法: sm3hash watch [选项] 目录...", "Usage: sm3hash watch [options] dir..."},
+	"baseline.usage":       {"用法: sm3hash baseline create [选项] -o 基线文件 目录\n      sm3hash baseline check [选项] -b 基线文件 [目录]", "Usage: sm3hash baseline create [options] -o baseline-file dir\n       sm3hash baseline check [options] -b baseline-file [dir]"},
+	"dedupe.scriptNote":    {"由 sm3hash dedupe 生成，执行前请检查。", "Generated by sm3hash dedupe; review before running."},
+	"dedupe.scriptGroup":   {"SM3 %s, %d 字节, 保留 %s", "SM3 %s, %d bytes, keeping %s"},
+	"msg.baselineCreated":  {"已记录 %d 个文件到 %s", "Recorded %d files in %s"},
+	"msg.watching":         {"正在监视 %d 个目录，结果写入 %s，按 Ctrl+C 结束", "Watching %d directories, results in %s; press Ctrl+C to stop"},
+	"msg.watchPolling":     {"目录变更通知不可用（%v），改为每 %s 扫描一次", "Change notifications unavailable (%v); scanning every %s instead"},
+	"err.notDir":           {"%s 不是目录", "%s is not a directory"},
+	"err.notDirectory":     {"不是目录", "not a directory"},
+	"err.notBaseline":      {"%s: 不是基线文件", "%s: not a baseline file"},
+	"err.baselineVersion":  {"%s: 不支持的基线版本 %d", "%s: unsupported baseline version %d"},
+	"err.baselineNeedsKey": {"%s: 基线使用 HMAC 封印，需要 -key-file", "%s: baseline has an HMAC seal; -key-file is required"},
+	"err.emptyKey":         {"%s: 密钥为空", "%s: key is empty"},
+	"err.writeBaseline":    {"写入基线失败: %v", "failed to write baseline: %v"},
+	"err.writeReport":      {"写入报告失败: %v", "failed to write report: %v"},
+	"err.writeScript":      {"写入脚本失败: %v", "failed to write script: %v"},
+	"err.scriptShell":      {"未知的脚本类型: %q（可选 sh、cmd）", "unknown script type: %q (choose sh, cmd)"},
+	"err.scriptAction":     {"未知的 -script-action: %q（可选 hardlink、remove）", "unknown -script-action: %q (choose hardlink, remove)"},
+	"err.nativeWatch":      {"当前平台不支持目录变更通知", "change notifications are not supported on this platform"},
+	"err.logLevel":         {"日志级别 %q 无效（可选 debug、info、warn、error）", "invalid log level %q (choose debug, info, warn, error)"},
+	"err.logFormat":        {"日志格式 %q 无效（可选 text、json）", "invalid log format %q (choose text, json)"},
+	"err.cacheCorrupt":     {"缓存文件损坏，已改名为 %s.bad", "cache file is corrupt and was renamed to %s.bad"},
+	"err.settingsCorrupt":  {"设置文件损坏，已改名为 %s.bad", "settings file is corrupt and was renamed to %s.bad"},
+	"err.settingsTooNew":   {"设置文件来自更新的版本，本次运行不保存设置", "settings file is from a newer version; settings will not be saved this run"},
+	"err.reportFormat":     {"未知的报告格式: %q（可选 text、json、csv）", "unknown report format: %q (choose text, json, csv)"},
+	"err.sidecarFormat":    {"未知的校验文件格式: %q（可选 gnu、bsd）", "unknown checksum file format: %q (choose gnu, bsd)"},
+	"err.noSidecar":        {"没有校验文件", "no checksum file"},
+	"flag.requireSidecar":  {"校验时把缺少校验文件（MISSING）算作失败", "Count files without a checksum file (MISSING) as failures when verifying"},
+	"err.sidecarMissing":   {"%s: 校验文件中没有 %s 的摘要", "%s: checksum file has no digest for %s"},
+	"err.attrMissing":      {"未找到已保存的摘要", "no stored digest found"},
+	"err.attrFormat":       {"摘要属性格式错误: %q", "malformed digest attribute: %q"},
+	"err.ioStrategy":       {"未知的读取策略: %q（可选 plain、readahead、mmap、direct）", "unknown read strategy: %q (choose plain, readahead, mmap, direct)"},
+	"err.textEncoding":     {"未知的文本编码: %q（可选 utf-8、gb18030、utf-16le）", "unknown text encoding: %q (choose utf-8, gb18030, utf-16le)"},
+	"err.newline":          {"未知的换行方式: %q（可选 keep、lf、crlf）", "unknown newline mode: %q (choose keep, lf, crlf)"},
+	"err.hexInput":         {"十六进制输入无效: %v", "invalid hex input: %v"},
+	"err.base64Input":      {"Base64 输入无效", "invalid Base64 input"},
+	"err.digestFormat":     {"未知的摘要编码: %q（可选 %s）", "unknown digest encoding: %q (choose %s)"},
+	"err.digestUnknown":    {"无法识别的 SM3 摘要: %q", "unrecognized SM3 digest: %q"},
+	"err.selfTest":         {"SM3 自检未通过（%s），拒绝计算摘要", "SM3 self-test failed (%s); refusing to compute digests"},
+}
+
+// tr 返回当前语言的文本，有参数时按 fmt.Sprintf 格式化；未定义的键原样返回，便于发现遗漏。
+func tr(key string, args ...any) string {
+	return trLang(currentLang, key, args...)
+}
+
+// trLang 与 tr 相同，但使用指定的语言，供按请求选择语言的 HTTP 接口使用。
+func trLang(l language, key string, args ...any) string {
+	m, ok := catalog[key]
+	if !ok {
+		return key
+	}
+	s := m.text(l)
+	if s == "" {
+		s = m.zh
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+// localizedError 的文本在输出时按当前语言查找；值可比较，errors.Is 照常工作。
+type localizedError string
+
+func (e localizedError) Error() string { return tr(string(e)) }
+
+// parseLanguage 解析语言代码；"auto" 或空串按环境检测。
+func parseLanguage(s string) (language, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "auto" {
+		return detectLanguage(), nil
+	}
+	if l, ok := languageTag(s); ok {
+		return l, nil
+	}
+	return langZH, errors.New(tr("msg.badLanguage", s))
+}
+
+// languageTag 按前缀识别语言标签，如 zh-CN、en_US.UTF-8。
+func languageTag(tag string) (language, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for i, name := range languageNames {
+		if strings.HasPrefix(tag, name) {
+			return language(i), true
+		}
+	}
+	return langZH, false
+}
+
+// detectLanguage 依次查看 SM3HASH_LANG、LC_ALL、LC_MESSAGES、LANG 和系统界面语言；
+// 找到非中文的区域设置时使用英文，什么都没有时保持中文。
+func detectLanguage() language {
+	locale := ""
+	for _, env := range []string{"SM3HASH_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			locale = v
+			break
+		}
+	}
+	if locale == "" {
+		locale = systemLocale()
+	}
+	locale = strings.ToLower(locale)
+	switch {
+	case locale == "" || strings.HasPrefix(locale, "zh"):
+		return langZH
+	case locale == "c" || locale == "posix" || strings.HasPrefix(locale, "c."):
+		// 未设置区域的终端与服务环境：保持原有的中文输出，避免改变脚本看到的内容。
+		return langZH
+	}
+	return langEN
+}
+
+// langFromArgs 在解析选项前找出 -lang，使选项说明本身也使用所选语言。
+func langFromArgs(args []string) (string, bool) {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") || name != "lang" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// systemLocale 在 Unix 上没有环境变量之外的来源，由 detectLanguage 读取 LANG 等变量。
+func systemLocale() string { return "" }
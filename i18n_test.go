@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var formatVerb = regexp.MustCompile(`%[-+# 0]*[0-9.]*[a-zA-Z%]`)
+
+// TestCatalog 检查每条消息都有全部语言的文本，且各语言的格式占位符与中文相同、顺序一致。
+func TestCatalog(t *testing.T) {
+	for k, m := range catalog {
+		want := strings.Join(formatVerb.FindAllString(m.zh, -1), " ")
+		for l := langZH; int(l) < len(languageNames); l++ {
+			s := m.text(l)
+			if s == "" {
+				t.Errorf("%s: no %s translation", k, languageNames[l])
+				continue
+			}
+			if got := strings.Join(formatVerb.FindAllString(s, -1), " "); got != want {
+				t.Errorf("%s: %s format verbs %q, zh has %q", k, languageNames[l], got, want)
+			}
+		}
+	}
+}
+
+// keyArgs 给出以消息键为参数的函数及键所在的参数位置。
+var keyArgs = map[string]int{"tr": 0, "localizedError": 0, "trLang": 1, "writeError": 4, "apiError": 2}
+
+// TestCatalogKeysExist 确认源码中传给 tr、trLang、localizedError、writeError 和 apiError 的键都在目录里，
+// 避免键名拼错后界面上直接显示键名。
+func TestCatalogKeysExist(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	checked := 0
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			var fun ast.Expr
+			var args []ast.Expr
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				fun, args = n.Fun, n.Args
+			case *ast.CompositeLit:
+				fun, args = n.Type, n.Elts
+			default:
+				return true
+			}
+			id, ok := fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			i, ok := keyArgs[id.Name]
+			if !ok || i >= len(args) {
+				return true
+			}
+			lit, ok := args[i].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			key, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			checked++
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("%s: message key %q is not in the catalog", fset.Position(lit.Pos()), key)
+			}
+			return true
+		})
+	}
+	if checked == 0 {
+		t.Error("no message keys found in the sources")
+	}
+}
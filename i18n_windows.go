@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+var procGetUserDefaultUILanguage = syscall.NewLazyDLL("kernel32.dll").NewProc("GetUserDefaultUILanguage")
+
+// systemLocale 按 Windows 界面语言返回 "zh" 或 "en"。
+func systemLocale() string {
+	id, _, _ := procGetUserDefaultUILanguage.Call()
+	if id == 0 {
+		return ""
+	}
+	const langChinese = 0x04 // PRIMARYLANGID
+	if id&0x3ff == langChinese {
+		return "zh"
+	}
+	return "en"
+}
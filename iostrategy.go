@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"runtime/debug"
+	"strings"
+	"unsafe"
+)
+
+// 文件读取策略：plain 用单个缓冲区顺序读取；readahead 用两个缓冲区，读取协程与计算重叠，适合网络共享等
+// 高延迟存储；mmap 把文件映射到内存直接计算（仅 Linux，其他平台回退为 plain）；direct 绕过页缓存
+// （Linux O_DIRECT、Windows FILE_FLAG_NO_BUFFERING），计算大量文件时不挤掉其他程序的缓存，
+// 文件系统不支持时回退为普通打开。缓冲区大小对 plain、readahead、direct 是每次读取的字节数，
+// 对 mmap 是两次取消检查之间计算的字节数。
+
+type ioStrategy int
+
+const (
+	ioPlain ioStrategy = iota
+	ioReadAhead
+	ioMmap
+	ioDirect
+)
+
+var ioStrategyNames = []string{"plain", "readahead", "mmap", "direct"}
+
+func (s ioStrategy) String() string { return ioStrategyNames[s] }
+
+func parseIOStrategy(s string) (ioStrategy, error) {
+	for i, name := range ioStrategyNames {
+		if strings.EqualFold(s, name) {
+			return ioStrategy(i), nil
+		}
+	}
+	return ioPlain, errors.New(tr("err.ioStrategy", s))
+}
+
+const (
+	defaultIOBufferKB = 256
+	minIOBufferKB     = 4
+	maxIOBufferKB     = 64 << 10
+	directAlign       = 4096 // O_DIRECT / NO_BUFFERING 要求缓冲区地址与读取长度按扇区对齐
+)
+
+// ioOptions 的零值是 plain、256 KiB。
+type ioOptions struct {
+	Strategy ioStrategy
+	BufferKB int // 0 表示默认值；超出 4 KiB～64 MiB 时取边界值
+}
+
+func (o ioOptions) bufferSize() int {
+	kb := o.BufferKB
+	if kb == 0 {
+		kb = defaultIOBufferKB
+	}
+	return max(minIOBufferKB, min(kb, maxIOBufferKB)) << 10
+}
+
+func (o *ioOptions) addFlags(flags *flag.FlagSet) {
+	flags.Func("io", tr("flag.io"), func(v string) error {
+		s, err := parseIOStrategy(v)
+		o.Strategy = s
+		return err
+	})
+	flags.IntVar(&o.BufferKB, "buffer-kb", defaultIOBufferKB, tr("flag.bufferKB"))
+}
+
+// hashFile 按 opt.IO 的策略读取已打开的 f 并写入 d；size 为打开时的文件长度。
+func hashFile(d *sm3Digest, f *os.File, size int64, opt hashOptions, progress progressFunc) (int64, error) {
+	bufSize := opt.IO.bufferSize()
+	r := cancelReader{f, opt.Cancel}
+	switch opt.IO.Strategy {
+	case ioReadAhead:
+		return hashReadAhead(d, r, size, bufSize, progress)
+	case ioMmap:
+		n, err := hashMapped(d, f, size, bufSize, opt.Cancel, progress)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return n, err
+		}
+	case ioDirect:
+		return hashReaderBuf(d, r, size, alignedBuffer(bufSize), progress)
+	}
+	return hashReaderBuf(d, r, size, make([]byte, bufSize), progress)
+}
+
+// alignedBuffer 返回起始地址按 directAlign 对齐、长度向上取整到 directAlign 的缓冲区。
+func alignedBuffer(size int) []byte {
+	size = (size + directAlign - 1) &^ (directAlign - 1)
+	b := make([]byte, size+directAlign)
+	off := int(-uintptr(unsafe.Pointer(&b[0])) & (directAlign - 1))
+	return b[off : off+size : off+size]
+}
+
+// hashReadAhead 在读取协程中交替填充两个缓冲区，当前缓冲区计算期间下一段已在读取。
+func hashReadAhead(d *sm3Digest, r io.Reader, length int64, bufSize int, progress progressFunc) (int64, error) {
+	type chunk struct {
+		buf []byte
+		n   int
+		err error
+	}
+	free := make(chan []byte, 2)
+	filled := make(chan chunk, 2)
+	free <- make([]byte, bufSize)
+	free <- make([]byte, bufSize)
+	go func() {
+		for buf := range free {
+			n, err := r.Read(buf)
+			filled <- chunk{buf, n, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	// 读取协程送出带错误（含 EOF）的一段后即退出，此后不再有发送方。
+	defer close(free)
+	pt := newProgressThrottle(progress, length)
+	for c := range filled {
+		if c.n > 0 {
+			d.Write(c.buf[:c.n])
+			pt.add(c.n)
+		}
+		if c.err == io.EOF {
+			break
+		}
+		if c.err != nil {
+			return pt.total, c.err
+		}
+		free <- c.buf
+	}
+	pt.done()
+	return pt.total, nil
+}
+
+// hashMapped 把文件映射到内存后按 bufSize 分段计算，每段之前检查取消。映射期间文件被其他进程截短时，
+// 访问已不存在的页面会触发 SIGBUS，借 debug.SetPanicOnFault 转为 errFileChanged 而不是让进程崩溃。
+// 当前平台或文件不支持映射时返回 errors.ErrUnsupported，调用方改用普通读取。
+func hashMapped(d *sm3Digest, f *os.File, size int64, bufSize int, cancel <-chan struct{}, progress progressFunc) (n int64, err error) {
+	data, unmap, err := mapFile(f, size)
+	if err != nil {
+		return 0, err
+	}
+	defer unmap()
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if p := recover(); p != nil {
+			if _, ok := p.(interface{ Addr() uintptr }); !ok {
+				panic(p)
+			}
+			n, err = 0, &fs.PathError{Op: "read", Path: f.Name(), Err: errFileChanged}
+		}
+	}()
+	pt := newProgressThrottle(progress, size)
+	for off := 0; off < len(data); off += bufSize {
+		select {
+		case <-cancel:
+			return pt.total, errCanceled
+		default:
+		}
+		end := min(off+bufSize, len(data))
+		d.Write(data[off:end])
+		pt.add(end - off)
+	}
+	pt.done()
+	return pt.total, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testBufferKB = 64
+
+// TestIOStrategies 确认每种读取策略对各种长度的文件都得到与 sm3Reference 相同、因而彼此（含 plain）一致的摘要。
+func TestIOStrategies(t *testing.T) {
+	buf := testBufferKB << 10
+	sizes := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"smaller than buffer", 100},
+		{"one aligned block", directAlign},
+		{"aligned, not a buffer multiple", 3 * directAlign},
+		{"not aligned", 3*buf + 1234},
+		{"one byte short of buffer", buf - 1},
+		{"exactly one buffer", buf},
+		{"one byte over buffer", buf + 1},
+	}
+	dir := t.TempDir()
+	for _, sz := range sizes {
+		data := make([]byte, sz.size)
+		for i := range data {
+			data[i] = byte(i*13 + i>>8)
+		}
+		path := filepath.Join(dir, fmt.Sprint(sz.size))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		ref := sm3Reference(data)
+		want := fmt.Sprintf("%x", ref)
+		for s := range ioStrategyNames {
+			s := ioStrategy(s)
+			t.Run(sz.name+"/"+s.String(), func(t *testing.T) {
+				opt := hashOptions{IO: ioOptions{Strategy: s, BufferKB: testBufferKB}}
+				var last int64
+				got, err := computeSM3File(path, opt, func(done, total int64) { last = done })
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("got %s, want %s", got, want)
+				}
+				if last != int64(sz.size) {
+					t.Errorf("last progress %d, want %d", last, sz.size)
+				}
+			})
+		}
+	}
+}
+
+// TestIOStrategiesCancel 在读取中途关闭 Cancel，每种策略都应停止并返回 errCanceled。
+func TestIOStrategiesCancel(t *testing.T) {
+	const size = 4 << 20
+	path := filepath.Join(t.TempDir(), "big")
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for s := range ioStrategyNames {
+		s := ioStrategy(s)
+		t.Run(s.String(), func(t *testing.T) {
+			cancel := make(chan struct{})
+			opt := hashOptions{Cancel: cancel, IO: ioOptions{Strategy: s, BufferKB: minIOBufferKB}}
+			var last int64
+			_, err := computeSM3File(path, opt, func(done, total int64) {
+				if done > 0 && last == 0 {
+					close(cancel)
+				}
+				last = done
+			})
+			if !errors.Is(err, errCanceled) {
+				t.Errorf("err = %v, want errCanceled", err)
+			}
+			if last >= size {
+				t.Errorf("read all %d bytes after cancel", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 服务端路径任务：客户端提交服务器本地的路径，由守护进程在自己的磁盘上计算。
+// 每个任务有独立的 jobQueue，处理方式与界面的 safeProcessQueue 相同；只允许读取 AllowRoots 之下的文件。
+//
+//	POST   /v1/jobs               提交任务 {"paths": [...], "include": [...], "exclude": [...], "algorithms": ["sm3"]}
+//	GET    /v1/jobs               列出任务
+//	GET    /v1/jobs/{id}          任务状态与进度
+//	GET    /v1/jobs/{id}/results  结果（?offset=&limit= 分页）
+//	DELETE /v1/jobs/{id}          取消任务
+
+type jobSpec struct {
+	Paths      []string `json:"paths"`
+	Include    []string `json:"include,omitempty"` // 文件名通配符，任一匹配即计算
+	Exclude    []string `json:"exclude,omitempty"` // 文件名通配符，任一匹配即跳过
+	Algorithms []string `json:"algorithms,omitempty"`
+}
+
+type jobResult struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	SM3   string `json:"sm3,omitempty"`
+	Error string `json:"error,omitempty"`
+	Kind  string `json:"kind,omitempty"`
+}
+
+type taskStatus string
+
+const (
+	taskQueued   taskStatus = "queued"
+	taskRunning  taskStatus = "running"
+	taskDone     taskStatus = "done"
+	taskCanceled taskStatus = "canceled"
+)
+
+// pathJob 是一个服务端任务。
+type pathJob struct {
+	mu       sync.Mutex
+	id       string
+	spec     jobSpec
+	state    taskStatus
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	queue    *jobQueue
+	progress batchProgress
+	results  []jobResult
+	skipped  []string
+	sum      runSummary
+	cancel   chan struct{}
+	once     sync.Once
+}
+
+type jobView struct {
+	ID         string     `json:"id"`
+	State      taskStatus `json:"state"`
+	Created    time.Time  `json:"created"`
+	Started    *time.Time `json:"started,omitempty"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	Paths      []string   `json:"paths"`
+	FilesTotal int        `json:"files_total"`
+	FilesDone  int        `json:"files_done"`
+	BytesTotal int64      `json:"bytes_total"`
+	BytesDone  int64      `json:"bytes_done"`
+	Percent    int        `json:"percent"`
+	Rate       float64    `json:"bytes_per_second"`
+	ETA        float64    `json:"eta_seconds"`
+	OK         int        `json:"ok"`
+	Failed     int        `json:"failed"`
+	Skipped    []string   `json:"skipped,omitempty"`
+}
+
+func (j *pathJob) view() jobView {
+	snap := j.progress.Snapshot()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	v := jobView{
+		ID: j.id, State: j.state, Created: j.created, Paths: j.spec.Paths,
+		FilesTotal: snap.TotalFiles, FilesDone: snap.DoneFiles,
+		BytesTotal: snap.TotalBytes, BytesDone: snap.DoneBytes,
+		Percent: snap.Percent(), Rate: snap.Rate, ETA: snap.ETA.Seconds(),
+		OK: j.sum.OK, Failed: j.sum.Failed, Skipped: j.skipped,
+	}
+	if !j.started.IsZero() {
+		t := j.started
+		v.Started = &t
+	}
+	if !j.finished.IsZero() {
+		t := j.finished
+		v.Finished = &t
+		if j.state == taskDone {
+			v.Percent = 100
+		}
+	}
+	return v
+}
+
+func (j *pathJob) Cancel() {
+	j.once.Do(func() { close(j.cancel) })
+}
+
+// jobManager 保存全部任务，限制同时运行的任务数，并只保留最近 keep 个已结束的任务。
+type jobManager struct {
+	mu     sync.Mutex
+	roots  []string
+	jobs   map[string]*pathJob
+	order  []string
+	nextID int
+	slots  chan struct{}
+	keep   int
+	io     ioOptions
+}
+
+func newJobManager(roots []string, maxRunning int, io ioOptions) *jobManager {
+	if maxRunning <= 0 {
+		maxRunning = 1
+	}
+	var resolved []string
+	for _, r := range roots {
+		if abs, err := filepath.Abs(r); err == nil {
+			if real, err := filepath.EvalSymlinks(abs); err == nil {
+				resolved = append(resolved, real)
+			}
+		}
+	}
+	m := &jobManager{roots: resolved, jobs: map[string]*pathJob{}, slots: make(chan struct{}, maxRunning), keep: 100, io: io}
+	hashMetrics.setQueue("jobs", m.pending)
+	return m
+}
+
+// pending 汇总所有任务中等待计算的文件数。
+func (m *jobManager) pending() int {
+	n := 0
+	for _, j := range m.List() {
+		n += j.queue.Pending()
+	}
+	return n
+}
+
+var errOutsideRoots = localizedError("err.outsideRoots")
+
+// apiError 是返回给客户端的请求错误：code 为稳定的英文错误码，消息在输出时按请求语言由 key 与 args 生成。
+type apiError struct {
+	status int
+	code   string
+	key    string
+	args   []any
+}
+
+func (e *apiError) Error() string { return tr(e.key, e.args...) }
+
+// allowed 解析符号链接后确认 p 位于某个允许的根目录之下；压缩包成员按压缩包本身判断。
+func (m *jobManager) allowed(p string) bool {
+	if archive, _, ok := splitArchivePath(p); ok {
+		p = archive
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return false
+	}
+	for _, root := range m.roots {
+		if _, ok := relInRoot(real, root); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s jobSpec) match(p string) bool {
+	name := filepath.Base(p)
+	for _, pat := range s.Exclude {
+		if ok, _ := filepath.Match(pat, name); ok {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, pat := range s.Include {
+		if ok, _ := filepath.Match(pat, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s jobSpec) validate() error {
+	if len(s.Paths) == 0 {
+		return &apiError{http.StatusBadRequest, "empty_paths", "api.emptyPaths", nil}
+	}
+	for _, a := range s.Algorithms {
+		if !strings.EqualFold(a, "sm3") {
+			return &apiError{http.StatusBadRequest, "unsupported_algorithm", "api.badAlgorithm", []any{a}}
+		}
+	}
+	for _, pat := range append(append([]string{}, s.Include...), s.Exclude...) {
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return &apiError{http.StatusBadRequest, "invalid_pattern", "api.badPattern", []any{pat}}
+		}
+	}
+	return nil
+}
+
+// Submit 检查路径后创建任务并在后台运行；请求本身有误时返回 *apiError。
+func (m *jobManager) Submit(spec jobSpec) (*pathJob, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	for _, p := range spec.Paths {
+		if !m.allowed(p) {
+			return nil, &apiError{http.StatusForbidden, "outside_roots", "api.outsideRoots", []any{p}}
+		}
+	}
+	m.mu.Lock()
+	m.nextID++
+	j := &pathJob{
+		id:      strconv.Itoa(m.nextID),
+		spec:    spec,
+		state:   taskQueued,
+		created: time.Now(),
+		queue:   newJobQueue(nil),
+		cancel:  make(chan struct{}),
+	}
+	j.progress.Reset()
+	m.jobs[j.id] = j
+	m.order = append(m.order, j.id)
+	m.pruneLocked()
+	m.mu.Unlock()
+	go m.run(j)
+	return j, nil
+}
+
+func (m *jobManager) pruneLocked() {
+	finished := 0
+	for i := len(m.order) - 1; i >= 0; i-- {
+		j := m.jobs[m.order[i]]
+		j.mu.Lock()
+		ended := j.state == taskDone || j.state == taskCanceled
+		j.mu.Unlock()
+		if !ended {
+			continue
+		}
+		if finished++; finished > m.keep {
+			delete(m.jobs, m.order[i])
+			m.order = append(m.order[:i], m.order[i+1:]...)
+		}
+	}
+}
+
+// run 展开路径并逐个计算；符号链接指向允许范围之外的文件会被跳过。
+func (m *jobManager) run(j *pathJob) {
+	select {
+	case m.slots <- struct{}{}:
+	case <-j.cancel:
+		m.finish(j, taskCanceled)
+		return
+	}
+	defer func() { <-m.slots }()
+	j.mu.Lock()
+	j.state, j.started = taskRunning, time.Now()
+	j.mu.Unlock()
+	rl := runLogger("serve", "job", j.id)
+
+	files, skipped := expandPaths(j.spec.Paths)
+	defer closeArchivesFor(files)
+	var accepted []string
+	for _, f := range files {
+		switch {
+		case !j.spec.match(f):
+		case !m.allowed(f):
+			skipped = append(skipped, fmt.Errorf("%s: %w", f, errOutsideRoots))
+		default:
+			accepted = append(accepted, f)
+		}
+	}
+	j.mu.Lock()
+	for _, err := range skipped {
+		j.skipped = append(j.skipped, err.Error())
+	}
+	j.sum.Skipped = len(skipped)
+	j.mu.Unlock()
+	hashMetrics.observeSkipped(len(skipped))
+	logSkipped(rl, skipped)
+	n, size := pendingTotals(j.queue.Add(accepted))
+	j.progress.Add(n, size)
+	logRunStart(rl, n, size)
+
+	opt := defaultHashOptions
+	opt.Cancel = j.cancel
+	opt.IO = m.io
+	for {
+		select {
+		case <-j.cancel:
+			m.finish(j, taskCanceled)
+			return
+		default:
+		}
+		e, ok := j.queue.Next()
+		if !ok {
+			break
+		}
+		fp := fileProgress{batch: &j.progress}
+		start := time.Now()
+		digest, err := computeSM3File(e.Path, opt, fp.update)
+		fp.finish(e.Size)
+		hashMetrics.observeFile(e.Size, time.Since(start), false, err)
+		logFileResult(rl, e.Path, e.Size, digest, time.Since(start), false, err)
+		j.queue.Finish(e.ID, err)
+		if errors.Is(err, errCanceled) {
+			m.finish(j, taskCanceled)
+			return
+		}
+		r := jobResult{Path: e.Path, Size: e.Size, SM3: digest}
+		if err != nil {
+			r.Error, r.Kind = err.Error(), classifyError(err).String()
+		}
+		j.mu.Lock()
+		j.results = append(j.results, r)
+		j.sum.add(err)
+		j.mu.Unlock()
+	}
+	m.finish(j, taskDone)
+}
+
+// finish 记录任务结束；取消时清掉尚未计算的条目，使队列长度只反映还会计算的文件。
+func (m *jobManager) finish(j *pathJob, state taskStatus) {
+	if state == taskCanceled {
+		j.queue.RemovePending()
+	}
+	j.mu.Lock()
+	j.state, j.finished = state, time.Now()
+	sum, d := j.sum, j.finished.Sub(j.created)
+	j.mu.Unlock()
+	logRunFinish(runLogger("serve", "job", j.id, "state", string(state)), sum, d)
+}
+
+func (m *jobManager) Get(id string) (*pathJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	return j, ok
+}
+
+func (m *jobManager) List() []*pathJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*pathJob, 0, len(m.order))
+	for _, id := range m.order {
+		out = append(out, m.jobs[id])
+	}
+	return out
+}
+
+// ServeHTTP 处理 /v1/jobs 与 /v1/jobs/ 下的请求。
+func (m *jobManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(m.roots) == 0 {
+		writeError(w, r, http.StatusForbidden, "jobs_disabled", "api.jobsDisabled")
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/jobs"), "/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			views := []jobView{}
+			for _, j := range m.List() {
+				views = append(views, j.view())
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"jobs": views})
+		case http.MethodPost:
+			var spec jobSpec
+			dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&spec); err != nil {
+				writeError(w, r, http.StatusBadRequest, "invalid_request", "api.badRequest", err)
+				return
+			}
+			j, err := m.Submit(spec)
+			var ae *apiError
+			if errors.As(err, &ae) {
+				writeError(w, r, ae.status, ae.code, ae.key, ae.args...)
+				return
+			}
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, "internal", "api.internal", err)
+				return
+			}
+			w.Header().Set("Location", "/v1/jobs/"+j.id)
+			writeJSON(w, http.StatusCreated, j.view())
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "api.methodGetPost")
+		}
+		return
+	}
+
+	id, sub, _ := strings.Cut(rest, "/")
+	j, ok := m.Get(id)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "job_not_found", "api.jobNotFound", id)
+		return
+	}
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, j.view())
+	case sub == "" && r.Method == http.MethodDelete:
+		j.Cancel()
+		writeJSON(w, http.StatusAccepted, j.view())
+	case sub == "results" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, j.page(r))
+	default:
+		writeError(w, r, http.StatusNotFound, "not_found", "api.notFound")
+	}
+}
+
+// page 返回一页结果，按完成顺序排列。
+func (j *pathJob) page(r *http.Request) map[string]any {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 10000 {
+		limit = 1000
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	total := len(j.results)
+	offset = max(0, min(offset, total))
+	end := min(total, offset+limit)
+	page := append([]jobResult{}, j.results[offset:end]...)
+	return map[string]any{"id": j.id, "state": j.state, "total": total, "offset": offset, "results": page}
+}
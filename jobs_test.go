@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newJobsTestServer 建立允许目录 root（含 a、sub/b 两个文件）和其外的 outside 目录，
+// 并在 root 中放入指向 outside 的目录与文件符号链接；不支持符号链接时跳过。
+func newJobsTestServer(t *testing.T) (srv *sm3Server, root, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	root, outside = filepath.Join(base, "root"), filepath.Join(base, "outside")
+	for _, d := range []string{filepath.Join(root, "sub"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for p, data := range map[string]string{
+		filepath.Join(root, "a"):         "abc",
+		filepath.Join(root, "sub", "b"):  "",
+		filepath.Join(outside, "secret"): "secret",
+	} {
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link-out")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultServeConfig
+	cfg.AllowRoots = []string{root}
+	return newSM3Server(cfg), root, outside
+}
+
+func jobsRequest(t *testing.T, h http.Handler, method, target, body string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: response %q is not JSON: %v", method, target, rec.Body, err)
+	}
+	return rec.Code, out
+}
+
+func submitPaths(paths ...string) string {
+	b, _ := json.Marshal(jobSpec{Paths: paths})
+	return string(b)
+}
+
+// waitJob 轮询任务直到进入 state。
+func waitJob(t *testing.T, h http.Handler, id string, state taskStatus) map[string]any {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, out := jobsRequest(t, h, "GET", "/v1/jobs/"+id, "")
+		if out["state"] == string(state) {
+			return out
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %v, want %s", id, out["state"], state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobsSubmitAndResults(t *testing.T) {
+	srv, root, _ := newJobsTestServer(t)
+	code, out := jobsRequest(t, srv, "POST", "/v1/jobs", submitPaths(root))
+	if code != http.StatusCreated {
+		t.Fatalf("submit = %d %v", code, out)
+	}
+	id := out["id"].(string)
+	view := waitJob(t, srv, id, taskDone)
+	if view["files_total"] != 2.0 || view["ok"] != 2.0 || view["percent"] != 100.0 {
+		t.Errorf("job view = %v", view)
+	}
+	// 指向允许目录之外文件的符号链接在展开后被跳过而不是被计算；指向目录的符号链接不进入。
+	if skipped, _ := view["skipped"].([]any); len(skipped) != 1 || !strings.Contains(skipped[0].(string), "escape") {
+		t.Errorf("skipped = %v, want only the file symlink", view["skipped"])
+	}
+
+	_, page := jobsRequest(t, srv, "GET", "/v1/jobs/"+id+"/results?limit=10", "")
+	want := map[string]string{
+		filepath.Join(root, "a"):        sm3OneShot([]byte("abc")),
+		filepath.Join(root, "sub", "b"): sm3OneShot(nil),
+	}
+	results, _ := page["results"].([]any)
+	if len(results) != len(want) {
+		t.Fatalf("results = %v", page)
+	}
+	for _, r := range results {
+		r := r.(map[string]any)
+		if p := r["path"].(string); r["sm3"] != want[p] {
+			t.Errorf("%s: sm3 = %v, want %s", p, r["sm3"], want[p])
+		}
+	}
+
+	if code, out := jobsRequest(t, srv, "GET", "/v1/jobs/"+id+"/nope", ""); code != http.StatusNotFound || out["code"] != "not_found" {
+		t.Errorf("unknown endpoint = %d %v", code, out)
+	}
+	code, list := jobsRequest(t, srv, "GET", "/v1/jobs", "")
+	if jobs, _ := list["jobs"].([]any); code != http.StatusOK || len(jobs) != 1 {
+		t.Errorf("list = %d %v", code, list)
+	}
+}
+
+func TestJobsRejectsRequests(t *testing.T) {
+	srv, root, outside := newJobsTestServer(t)
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		code   string
+	}{
+		{"outside root", "POST", "/v1/jobs", submitPaths(outside), 403, "outside_roots"},
+		{"dot-dot escape", "POST", "/v1/jobs", submitPaths(filepath.Join(root, "..", "outside", "secret")), 403, "outside_roots"},
+		{"symlinked dir", "POST", "/v1/jobs", submitPaths(filepath.Join(root, "link-out")), 403, "outside_roots"},
+		{"symlinked file", "POST", "/v1/jobs", submitPaths(filepath.Join(root, "escape")), 403, "outside_roots"},
+		{"one bad path", "POST", "/v1/jobs", submitPaths(filepath.Join(root, "a"), outside), 403, "outside_roots"},
+		{"empty paths", "POST", "/v1/jobs", `{"paths": []}`, 400, "empty_paths"},
+		{"algorithm", "POST", "/v1/jobs", `{"paths": ["` + filepath.ToSlash(root) + `"], "algorithms": ["md5"]}`, 400, "unsupported_algorithm"},
+		{"pattern", "POST", "/v1/jobs", `{"paths": ["` + filepath.ToSlash(root) + `"], "include": ["["]}`, 400, "invalid_pattern"},
+		{"unknown field", "POST", "/v1/jobs", `{"path": "x"}`, 400, "invalid_request"},
+		{"bad json", "POST", "/v1/jobs", `{`, 400, "invalid_request"},
+		{"method", "PUT", "/v1/jobs", "", 405, "method_not_allowed"},
+		{"missing job", "GET", "/v1/jobs/999", "", 404, "job_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out := jobsRequest(t, srv, tt.method, tt.target, tt.body)
+			if code != tt.status || out["code"] != tt.code || out["error"] == "" {
+				t.Errorf("%s %s = %d %v, want %d %s", tt.method, tt.target, code, out, tt.status, tt.code)
+			}
+		})
+	}
+	if jobs := srv.jobs.List(); len(jobs) != 0 {
+		t.Errorf("rejected requests created %d jobs", len(jobs))
+	}
+
+	disabled := newSM3Server(defaultServeConfig)
+	if code, out := jobsRequest(t, disabled, "GET", "/v1/jobs", ""); code != 403 || out["code"] != "jobs_disabled" {
+		t.Errorf("without -allow-root = %d %v", code, out)
+	}
+}
+
+func TestJobsCancel(t *testing.T) {
+	srv, root, _ := newJobsTestServer(t)
+	// 占满运行名额，任务停在排队状态。
+	for i := 0; i < cap(srv.jobs.slots); i++ {
+		srv.jobs.slots <- struct{}{}
+	}
+	_, out := jobsRequest(t, srv, "POST", "/v1/jobs", submitPaths(root))
+	id := out["id"].(string)
+	if code, out := jobsRequest(t, srv, "DELETE", "/v1/jobs/"+id, ""); code != http.StatusAccepted {
+		t.Fatalf("cancel = %d %v", code, out)
+	}
+	view := waitJob(t, srv, id, taskCanceled)
+	if view["files_done"] != 0.0 {
+		t.Errorf("canceled job view = %v", view)
+	}
+	for i := 0; i < cap(srv.jobs.slots); i++ {
+		<-srv.jobs.slots
+	}
+}
+
+// TestJobCancelDrainsQueue 确认运行中取消的任务不再把未计算的文件计入队列长度。
+func TestJobCancelDrainsQueue(t *testing.T) {
+	m := newJobManager(nil, 1, ioOptions{})
+	q, _, _ := newTestQueue(t, 1, 2, 3)
+	j := &pathJob{id: "1", state: taskRunning, queue: q, cancel: make(chan struct{})}
+	m.jobs[j.id], m.order = j, []string{j.id}
+	running, _ := q.Next()
+	if m.pending() != 2 {
+		t.Fatalf("pending before cancel = %d", m.pending())
+	}
+	q.Finish(running.ID, errCanceled)
+	m.finish(j, taskCanceled)
+	if m.pending() != 0 || len(q.Snapshot()) != 1 {
+		t.Errorf("after cancel: pending %d, queue %+v", m.pending(), q.Snapshot())
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// 非 Windows 平台没有图形界面，只提供命令行模式。
+func main() {
+	os.Exit(runCLI(os.Args[1:]))
+}
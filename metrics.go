@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 进程内指标：由各处的工作循环上报，按 Prometheus 文本格式（0.0.4）输出，不依赖第三方库。
+// 服务模式通过 GET /metrics 暴露；命令行用 -metrics 在结束时写成文件，供 node_exporter 的 textfile 收集器读取。
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// fileDurationBuckets 是单个文件耗时直方图的上界（秒）。
+var fileDurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300}
+
+type metrics struct {
+	mu        sync.Mutex
+	files     int64
+	bytes     int64
+	cacheHits int64
+	skipped   int64
+	errors    map[errKind]int64
+	streams   int64
+	streamB   int64
+	durCounts []int64 // 每个桶内的次数（不累计），最后一个是 +Inf
+	durSum    float64
+	durCount  int64
+	queues    map[string]func() int
+}
+
+// hashMetrics 是进程唯一的指标集合。
+var hashMetrics = newMetrics()
+
+func newMetrics() *metrics {
+	return &metrics{
+		errors:    map[errKind]int64{},
+		durCounts: make([]int64, len(fileDurationBuckets)+1),
+		queues:    map[string]func() int{},
+	}
+}
+
+// observeFile 记录一个文件的处理结果；失败的文件只计入错误与耗时，不计字节。
+func (m *metrics) observeFile(size int64, d time.Duration, cached bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.errors[classifyError(err)]++
+	} else {
+		m.files++
+		m.bytes += size
+		if cached {
+			m.cacheHits++
+		}
+	}
+	sec := d.Seconds()
+	i := sort.SearchFloat64s(fileDurationBuckets, sec)
+	m.durCounts[i]++
+	m.durSum += sec
+	m.durCount++
+}
+
+// observeSkipped 记录展开路径时跳过的条目数。
+func (m *metrics) observeSkipped(n int) {
+	m.mu.Lock()
+	m.skipped += int64(n)
+	m.mu.Unlock()
+}
+
+// observeStream 记录一次 HTTP 流式计算。
+func (m *metrics) observeStream(n int64) {
+	m.mu.Lock()
+	m.streams++
+	m.streamB += n
+	m.mu.Unlock()
+}
+
+// setQueue 登记一个队列的等待数，输出为 sm3hash_queue_depth{queue="name"}。
+func (m *metrics) setQueue(name string, pending func() int) {
+	m.mu.Lock()
+	m.queues[name] = pending
+	m.mu.Unlock()
+}
+
+// errKindLabel 是错误分类在指标标签中的名字。
+var errKindLabel = map[errKind]string{
+	errKindNotFound:   "not_found",
+	errKindPermission: "permission",
+	errKindIO:         "io",
+	errKindChanged:    "changed",
+	errKindVerify:     "verify",
+	errKindCanceled:   "canceled",
+}
+
+func formatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// write 输出全部指标。错误分类即使为 0 也输出，保证序列稳定。HELP 文本固定为英文，不随界面语言变化。
+// 队列等待数在持有 m.mu 之前取得：pending 回调会获取各队列自己的锁，不能嵌套在指标锁内。
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	queues := make(map[string]func() int, len(m.queues))
+	for name, pending := range m.queues {
+		queues[name] = pending
+	}
+	m.mu.Unlock()
+	depth := make(map[string]int, len(queues))
+	names := make([]string, 0, len(queues))
+	for name, pending := range queues {
+		depth[name] = pending()
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	m.mu.Lock()
+	counter := func(name, help string, v int64) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	counter("sm3hash_files_hashed_total", "Files hashed successfully.", m.files)
+	counter("sm3hash_bytes_hashed_total", "Bytes of files hashed successfully.", m.bytes)
+	counter("sm3hash_cache_hits_total", "Files answered from the incremental hash cache.", m.cacheHits)
+	counter("sm3hash_files_skipped_total", "Entries skipped while expanding paths.", m.skipped)
+
+	buf.WriteString("# HELP sm3hash_errors_total Failed files by error kind.\n# TYPE sm3hash_errors_total counter\n")
+	for k := errKindNotFound; k <= errKindCanceled; k++ {
+		fmt.Fprintf(&buf, "sm3hash_errors_total{kind=%q} %d\n", errKindLabel[k], m.errors[k])
+	}
+
+	buf.WriteString("# HELP sm3hash_file_duration_seconds Time spent hashing a single file.\n# TYPE sm3hash_file_duration_seconds histogram\n")
+	var cum int64
+	for i, le := range fileDurationBuckets {
+		cum += m.durCounts[i]
+		fmt.Fprintf(&buf, "sm3hash_file_duration_seconds_bucket{le=%q} %d\n", formatMetricValue(le), cum)
+	}
+	fmt.Fprintf(&buf, "sm3hash_file_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.durCount)
+	fmt.Fprintf(&buf, "sm3hash_file_duration_seconds_sum %s\n", formatMetricValue(m.durSum))
+	fmt.Fprintf(&buf, "sm3hash_file_duration_seconds_count %d\n", m.durCount)
+
+	counter("sm3hash_stream_requests_total", "HTTP streaming hash requests.", m.streams)
+	counter("sm3hash_stream_bytes_total", "Bytes hashed by HTTP streaming requests.", m.streamB)
+
+	m.mu.Unlock()
+
+	if len(names) > 0 {
+		buf.WriteString("# HELP sm3hash_queue_depth Files waiting to be hashed.\n# TYPE sm3hash_queue_depth gauge\n")
+		for _, name := range names {
+			fmt.Fprintf(&buf, "sm3hash_queue_depth{queue=%q} %d\n", name, depth[name])
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeMetricsFile 先写临时文件再改名，textfile 收集器不会读到半个文件。
+func writeMetricsFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sm3hash-metrics-*")
+	if err != nil {
+		return err
+	}
+	if err := hashMetrics.write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	os.Chmod(tmp.Name(), 0o644)
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	m := newMetrics()
+	m.observeFile(100, 20*time.Millisecond, false, nil)
+	m.observeFile(50, 2*time.Second, true, nil)
+	m.observeFile(0, time.Millisecond, false, &fs.PathError{Op: "open", Path: "x", Err: fs.ErrNotExist})
+	m.observeFile(0, time.Millisecond, false, errors.New("boom"))
+	m.observeSkipped(3)
+	m.observeStream(7)
+	// 回调本身再上报指标：write 若在持有 m.mu 时调用回调就会死锁。
+	m.setQueue("gui", func() int { m.observeSkipped(0); return 4 })
+
+	var buf bytes.Buffer
+	if err := m.write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"sm3hash_files_hashed_total 2\n",
+		"sm3hash_bytes_hashed_total 150\n",
+		"sm3hash_cache_hits_total 1\n",
+		"sm3hash_files_skipped_total 3\n",
+		`sm3hash_errors_total{kind="not_found"} 1` + "\n",
+		`sm3hash_errors_total{kind="io"} 1` + "\n",
+		`sm3hash_errors_total{kind="canceled"} 0` + "\n",
+		`sm3hash_file_duration_seconds_bucket{le="0.05"} 3` + "\n",
+		`sm3hash_file_duration_seconds_bucket{le="+Inf"} 4` + "\n",
+		"sm3hash_file_duration_seconds_count 4\n",
+		"sm3hash_stream_bytes_total 7\n",
+		`sm3hash_queue_depth{queue="gui"} 4` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "# HELP") && strings.IndexFunc(line, func(r rune) bool { return r > unicode.MaxASCII }) >= 0 {
+			t.Errorf("HELP text is not ASCII: %s", line)
+		}
+	}
+}
+
+// TestMetricsQueueDepthAfterCancel 确认取消的任务不再计入 sm3hash_queue_depth。
+func TestMetricsQueueDepthAfterCancel(t *testing.T) {
+	m := newJobManager(nil, 1, ioOptions{})
+	q, _, _ := newTestQueue(t, 1, 2, 3)
+	j := &pathJob{id: "1", state: taskRunning, queue: q, cancel: make(chan struct{})}
+	m.jobs[j.id], m.order = j, []string{j.id}
+	reg := newMetrics()
+	reg.setQueue("jobs", m.pending)
+	depth := func() string {
+		var buf bytes.Buffer
+		reg.write(&buf)
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, "sm3hash_queue_depth{") {
+				return line
+			}
+		}
+		return ""
+	}
+	if got := depth(); got != `sm3hash_queue_depth{queue="jobs"} 3` {
+		t.Errorf("before cancel: %s", got)
+	}
+	m.finish(j, taskCanceled)
+	if got := depth(); got != `sm3hash_queue_depth{queue="jobs"} 0` {
+		t.Errorf("after cancel: %s", got)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Linux 没有共享模式，denyWrite 仅依赖读取前后的稳定性检查。direct 时以 O_DIRECT 打开，
+// tmpfs 等不支持的文件系统返回 EINVAL，此时改为普通打开。
+func openForHash(path string, denyWrite, direct bool) (*os.File, error) {
+	if direct {
+		f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_DIRECT, 0)
+		if !errors.Is(err, syscall.EINVAL) {
+			return f, err
+		}
+	}
+	return os.Open(path)
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !linux
+
+package main
+
+import "os"
+
+// 非 Windows 平台没有共享模式，denyWrite 仅依赖读取前后的稳定性检查；这里也不支持绕过页缓存，direct 按普通方式打开。
+func openForHash(path string, denyWrite, direct bool) (*os.File, error) {
+	return os.Open(path)
+}
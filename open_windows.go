@@ -0,0 +1,36 @@
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// fileFlagNoBuffering 让读取绕过系统缓存，要求缓冲区地址与读取长度按扇区对齐（见 alignedBuffer）。
+const fileFlagNoBuffering = 0x20000000
+
+// openForHash 在 denyWrite 时只共享读权限打开文件，其他进程在读取期间无法写入；direct 时不经过系统缓存。
+func openForHash(path string, denyWrite, direct bool) (*os.File, error) {
+	if !denyWrite && !direct {
+		return os.Open(path)
+	}
+	share := uint32(syscall.FILE_SHARE_READ)
+	if !denyWrite {
+		share |= syscall.FILE_SHARE_WRITE
+	}
+	attrs := uint32(syscall.FILE_ATTRIBUTE_NORMAL)
+	if direct {
+		attrs |= fileFlagNoBuffering
+	}
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+	h, err := syscall.CreateFile(p, syscall.GENERIC_READ, share, nil, syscall.OPEN_EXISTING, attrs, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// batchProgress 汇总整个队列的进度（字节、文件数、平滑速率、剩余时间），界面和命令行共用。
+type batchProgress struct {
+	mu         sync.Mutex
+	totalBytes int64
+	doneBytes  int64
+	totalFiles int
+	doneFiles  int
+	rate       float64 // 指数平滑后的字节/秒
+	sampleAt   time.Time
+	sampleDone int64
+}
+
+type progressSnapshot struct {
+	DoneBytes  int64
+	TotalBytes int64
+	DoneFiles  int
+	TotalFiles int
+	Rate       float64 // 字节/秒
+	ETA        time.Duration
+}
+
+const (
+	rateSampleInterval = 500 * time.Millisecond
+	rateSmoothing      = 0.3
+)
+
+func (p *batchProgress) Reset() {
+	p.mu.Lock()
+	p.totalBytes, p.doneBytes = 0, 0
+	p.totalFiles, p.doneFiles = 0, 0
+	p.rate = 0
+	p.sampleAt, p.sampleDone = time.Now(), 0
+	p.mu.Unlock()
+}
+
+// Add 登记新加入的文件数与总字节数；移除时传入负值。
+func (p *batchProgress) Add(files int, bytes int64) {
+	p.mu.Lock()
+	p.totalFiles += files
+	p.totalBytes += bytes
+	p.mu.Unlock()
+}
+
+// Advance 累加已读取字节，重试时可为负值。
+func (p *batchProgress) Advance(n int64) {
+	p.mu.Lock()
+	p.doneBytes += n
+	p.sample(time.Now())
+	p.mu.Unlock()
+}
+
+// FileDone 标记一个文件结束；unread 为失败时未读到的字节，计入已完成以保持总量一致。
+func (p *batchProgress) FileDone(unread int64) {
+	p.mu.Lock()
+	p.doneFiles++
+	if unread > 0 {
+		p.doneBytes += unread
+		p.sampleDone += unread
+	}
+	p.mu.Unlock()
+}
+
+func (p *batchProgress) sample(now time.Time) {
+	if p.sampleAt.IsZero() {
+		p.sampleAt, p.sampleDone = now, p.doneBytes
+		return
+	}
+	dt := now.Sub(p.sampleAt)
+	if dt < rateSampleInterval {
+		return
+	}
+	inst := float64(p.doneBytes-p.sampleDone) / dt.Seconds()
+	if inst < 0 {
+		inst = 0
+	}
+	if p.rate == 0 {
+		p.rate = inst
+	} else {
+		p.rate = rateSmoothing*inst + (1-rateSmoothing)*p.rate
+	}
+	p.sampleAt, p.sampleDone = now, p.doneBytes
+}
+
+func (p *batchProgress) Snapshot() progressSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sample(time.Now())
+	s := progressSnapshot{
+		DoneBytes:  p.doneBytes,
+		TotalBytes: p.totalBytes,
+		DoneFiles:  p.doneFiles,
+		TotalFiles: p.totalFiles,
+		Rate:       p.rate,
+	}
+	if s.DoneBytes > s.TotalBytes {
+		s.DoneBytes = s.TotalBytes
+	}
+	if s.DoneFiles > s.TotalFiles {
+		s.DoneFiles = s.TotalFiles
+	}
+	if s.Rate > 0 {
+		s.ETA = time.Duration(float64(s.TotalBytes-s.DoneBytes) / s.Rate * float64(time.Second))
+	}
+	return s
+}
+
+// Percent 按字节计算总体百分比；全是空文件时按文件数计算。
+func (s progressSnapshot) Percent() int {
+	switch {
+	case s.TotalBytes > 0:
+		return int(s.DoneBytes * 100 / s.TotalBytes)
+	case s.TotalFiles > 0:
+		return s.DoneFiles * 100 / s.TotalFiles
+	}
+	return 0
+}
+
+func (s progressSnapshot) String() string {
+	eta := "--:--:--"
+	if s.Rate > 0 {
+		eta = formatDuration(s.ETA)
+	}
+	return tr("progress.line",
+		s.DoneFiles, s.TotalFiles, formatBytes(s.DoneBytes), formatBytes(s.TotalBytes), s.Rate/1e6, eta)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatDuration(d time.Duration) string {
+	sec := int64(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+}
+
+// fileProgress 把单个文件的读取位置换算为增量，喂给 batchProgress。
+type fileProgress struct {
+	batch *batchProgress
+	last  int64
+}
+
+func (f *fileProgress) update(done, total int64) {
+	f.batch.Advance(done - f.last)
+	f.last = done
+}
+
+// finish 结束当前文件；失败时把未读部分一并计入。
+func (f *fileProgress) finish(size int64) {
+	f.batch.FileDone(size - f.last)
+}
@@ -0,0 +1,298 @@
+package main
+
+import "sync"
+
+// 任务队列模型：不依赖 Win32，界面列表与工作线程都通过它读写队列状态。
+
+type jobState int
+
+const (
+	jobPending jobState = iota
+	jobRunning
+	jobDone
+	jobFailed
+)
+
+func (s jobState) String() string {
+	switch s {
+	case jobPending:
+		return tr("state.pending")
+	case jobRunning:
+		return tr("state.running")
+	case jobDone:
+		return tr("state.done")
+	case jobFailed:
+		return tr("state.failed")
+	}
+	return tr("state.unknown")
+}
+
+type queueEntry struct {
+	ID    int
+	Path  string
+	Size  int64
+	State jobState
+	Kind  errKind
+	Err   string
+}
+
+// Status 返回用于展示的状态文本，失败时附带原因分类。
+func (e queueEntry) Status() string {
+	if e.State == jobFailed && e.Kind != errKindNone {
+		return e.State.String() + "(" + e.Kind.String() + ")"
+	}
+	return e.State.String()
+}
+
+type jobQueue struct {
+	mu      sync.Mutex
+	nextID  int
+	entries []*queueEntry
+	notify  func()
+}
+
+func newJobQueue(notify func()) *jobQueue {
+	return &jobQueue{nextID: 1, notify: notify}
+}
+
+func (q *jobQueue) changed() {
+	if q.notify != nil {
+		q.notify()
+	}
+}
+
+// Add 追加待计算文件，返回新条目（含分配的 ID）。文件大小在加入时记录，用于汇总进度。
+func (q *jobQueue) Add(paths []string) []queueEntry {
+	if len(paths) == 0 {
+		return nil
+	}
+	sizes := make([]int64, len(paths))
+	for i, p := range paths {
+		sizes[i] = pathSize(p)
+	}
+	q.mu.Lock()
+	added := make([]queueEntry, 0, len(paths))
+	for i, p := range paths {
+		e := &queueEntry{ID: q.nextID, Path: p, Size: sizes[i]}
+		q.nextID++
+		q.entries = append(q.entries, e)
+		added = append(added, *e)
+	}
+	q.mu.Unlock()
+	q.changed()
+	return added
+}
+
+// Next 取出排在最前的等待项并标记为计算中。
+func (q *jobQueue) Next() (queueEntry, bool) {
+	q.mu.Lock()
+	var found *queueEntry
+	for _, e := range q.entries {
+		if e.State == jobPending {
+			e.State = jobRunning
+			found = e
+			break
+		}
+	}
+	q.mu.Unlock()
+	if found == nil {
+		return queueEntry{}, false
+	}
+	q.changed()
+	return *found, true
+}
+
+// Finish 记录计算结果；err 为 nil 表示成功。
+func (q *jobQueue) Finish(id int, err error) {
+	q.mu.Lock()
+	e := q.find(id)
+	if e != nil {
+		e.Kind = classifyError(err)
+		if err != nil {
+			e.State = jobFailed
+			e.Err = err.Error()
+		} else {
+			e.State = jobDone
+			e.Err = ""
+		}
+	}
+	q.mu.Unlock()
+	if e != nil {
+		q.changed()
+	}
+}
+
+// Remove 删除指定条目，正在计算的条目不可删除。返回实际删除的条目。
+func (q *jobQueue) Remove(ids ...int) []queueEntry {
+	drop := idSet(ids)
+	q.mu.Lock()
+	kept := q.entries[:0]
+	var removed []queueEntry
+	for _, e := range q.entries {
+		if _, ok := drop[e.ID]; ok && e.State != jobRunning {
+			removed = append(removed, *e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	for i := len(kept); i < len(q.entries); i++ {
+		q.entries[i] = nil
+	}
+	q.entries = kept
+	q.mu.Unlock()
+	if len(removed) > 0 {
+		q.changed()
+	}
+	return removed
+}
+
+// Move 将等待项与相邻的等待项交换位置，delta 为 -1 上移、1 下移。
+func (q *jobQueue) Move(id, delta int) bool {
+	q.mu.Lock()
+	i := q.index(id)
+	j := i + delta
+	ok := i >= 0 && j >= 0 && j < len(q.entries) && delta != 0 &&
+		q.entries[i].State == jobPending && q.entries[j].State == jobPending
+	if ok {
+		e := q.entries[i]
+		if delta > 0 {
+			copy(q.entries[i:j], q.entries[i+1:j+1])
+		} else {
+			copy(q.entries[j+1:i+1], q.entries[j:i])
+		}
+		q.entries[j] = e
+	}
+	q.mu.Unlock()
+	if ok {
+		q.changed()
+	}
+	return ok
+}
+
+// Prioritize 将指定等待项按原有相对顺序移到所有等待项之前。
+func (q *jobQueue) Prioritize(ids ...int) int {
+	want := idSet(ids)
+	q.mu.Lock()
+	var front, rest []*queueEntry
+	first := -1
+	for i, e := range q.entries {
+		if e.State == jobPending && first < 0 {
+			first = i
+		}
+		if _, ok := want[e.ID]; ok && e.State == jobPending {
+			front = append(front, e)
+		} else if first >= 0 {
+			rest = append(rest, e)
+		}
+	}
+	if len(front) > 0 {
+		n := copy(q.entries[first:], front)
+		copy(q.entries[first+n:], rest)
+	}
+	q.mu.Unlock()
+	if len(front) > 0 {
+		q.changed()
+	}
+	return len(front)
+}
+
+// RetryFailed 将失败条目重新置为等待，返回这些条目。
+func (q *jobQueue) RetryFailed() []queueEntry {
+	q.mu.Lock()
+	var retried []queueEntry
+	for _, e := range q.entries {
+		if e.State == jobFailed {
+			e.State = jobPending
+			e.Kind = errKindNone
+			e.Err = ""
+			retried = append(retried, *e)
+		}
+	}
+	q.mu.Unlock()
+	if len(retried) > 0 {
+		q.changed()
+	}
+	return retried
+}
+
+// ClearFinished 移除已完成和失败的条目。
+func (q *jobQueue) ClearFinished() []queueEntry {
+	var ids []int
+	q.mu.Lock()
+	for _, e := range q.entries {
+		if e.State == jobDone || e.State == jobFailed {
+			ids = append(ids, e.ID)
+		}
+	}
+	q.mu.Unlock()
+	return q.Remove(ids...)
+}
+
+// RemovePending 移除所有等待中的条目，取消整个队列时使用。
+func (q *jobQueue) RemovePending() []queueEntry {
+	var ids []int
+	q.mu.Lock()
+	for _, e := range q.entries {
+		if e.State == jobPending {
+			ids = append(ids, e.ID)
+		}
+	}
+	q.mu.Unlock()
+	return q.Remove(ids...)
+}
+
+func (q *jobQueue) Pending() int {
+	n, _ := q.PendingTotals()
+	return n
+}
+
+// PendingTotals 返回等待项的数量与总字节数。
+func (q *jobQueue) PendingTotals() (files int, bytes int64) {
+	return pendingTotals(q.Snapshot())
+}
+
+// pendingTotals 汇总处于等待状态的条目数量与字节数。
+func pendingTotals(entries []queueEntry) (files int, bytes int64) {
+	for _, e := range entries {
+		if e.State == jobPending {
+			files++
+			bytes += e.Size
+		}
+	}
+	return files, bytes
+}
+
+// Snapshot 返回当前队列的副本，供界面展示。
+func (q *jobQueue) Snapshot() []queueEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]queueEntry, len(q.entries))
+	for i, e := range q.entries {
+		out[i] = *e
+	}
+	return out
+}
+
+func (q *jobQueue) index(id int) int {
+	for i, e := range q.entries {
+		if e.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *jobQueue) find(id int) *queueEntry {
+	if i := q.index(id); i >= 0 {
+		return q.entries[i]
+	}
+	return nil
+}
+
+func idSet(ids []int) map[int]struct{} {
+	m := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		m[id] = struct{}{}
+	}
+	return m
+}
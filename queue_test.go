@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestQueue 在临时目录中创建给定大小的文件并加入新队列，返回队列、条目和通知计数。
+func newTestQueue(t *testing.T, sizes ...int) (*jobQueue, []queueEntry, *int) {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, n := range sizes {
+		p := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(p, make([]byte, n), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	notified := new(int)
+	q := newJobQueue(func() { *notified++ })
+	return q, q.Add(paths), notified
+}
+
+func states(q *jobQueue) []jobState {
+	var out []jobState
+	for _, e := range q.Snapshot() {
+		out = append(out, e.State)
+	}
+	return out
+}
+
+func ids(q *jobQueue) []int {
+	var out []int
+	for _, e := range q.Snapshot() {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQueueAdd(t *testing.T) {
+	q, added, notified := newTestQueue(t, 10, 20, 30)
+	if len(added) != 3 || *notified != 1 {
+		t.Fatalf("added %d entries with %d notifications, want 3 and 1", len(added), *notified)
+	}
+	for i, e := range added {
+		if e.ID != i+1 || e.State != jobPending || e.Size != int64((i+1)*10) {
+			t.Errorf("entry %d = %+v", i, e)
+		}
+	}
+	if got := q.Add(nil); got != nil || *notified != 1 {
+		t.Errorf("Add(nil) = %v, notified %d times", got, *notified)
+	}
+	if files, bytes := q.PendingTotals(); files != 3 || bytes != 60 {
+		t.Errorf("PendingTotals = %d, %d; want 3, 60", files, bytes)
+	}
+}
+
+func TestQueueNextFinish(t *testing.T) {
+	q, _, _ := newTestQueue(t, 1, 2)
+	e, ok := q.Next()
+	if !ok || e.ID != 1 || e.State != jobRunning {
+		t.Fatalf("Next = %+v, %v", e, ok)
+	}
+	q.Finish(e.ID, nil)
+	e, _ = q.Next()
+	q.Finish(e.ID, &fs.PathError{Op: "open", Path: e.Path, Err: fs.ErrNotExist})
+	if _, ok := q.Next(); ok {
+		t.Fatal("Next returned an entry from a drained queue")
+	}
+	snap := q.Snapshot()
+	if snap[0].State != jobDone || snap[0].Err != "" {
+		t.Errorf("first entry = %+v, want done", snap[0])
+	}
+	if snap[1].State != jobFailed || snap[1].Kind != errKindNotFound || snap[1].Err == "" {
+		t.Errorf("second entry = %+v, want failed/not found", snap[1])
+	}
+	if q.Pending() != 0 {
+		t.Errorf("Pending = %d, want 0", q.Pending())
+	}
+}
+
+func TestQueueRetryFailed(t *testing.T) {
+	q, _, _ := newTestQueue(t, 5, 7, 9)
+	for i := 0; i < 3; i++ {
+		e, _ := q.Next()
+		var err error
+		if i != 1 {
+			err = errFileChanged
+		}
+		q.Finish(e.ID, err)
+	}
+	retried := q.RetryFailed()
+	if len(retried) != 2 || retried[0].ID != 1 || retried[1].ID != 3 {
+		t.Fatalf("RetryFailed = %+v", retried)
+	}
+	want := []jobState{jobPending, jobDone, jobPending}
+	for i, s := range states(q) {
+		if s != want[i] {
+			t.Errorf("state[%d] = %v, want %v", i, s, want[i])
+		}
+	}
+	if files, bytes := q.PendingTotals(); files != 2 || bytes != 14 {
+		t.Errorf("PendingTotals after retry = %d, %d; want 2, 14", files, bytes)
+	}
+	if got := q.RetryFailed(); len(got) != 0 {
+		t.Errorf("second RetryFailed = %+v, want none", got)
+	}
+}
+
+func TestQueueRemove(t *testing.T) {
+	q, _, _ := newTestQueue(t, 1, 1, 1, 1)
+	running, _ := q.Next()
+	removed := q.Remove(running.ID, 3, 99)
+	if len(removed) != 1 || removed[0].ID != 3 {
+		t.Fatalf("Remove = %+v, want only entry 3 (running entries stay)", removed)
+	}
+	if got := ids(q); !equalInts(got, []int{1, 2, 4}) {
+		t.Errorf("ids after Remove = %v", got)
+	}
+	q.Finish(running.ID, nil)
+	if got := q.ClearFinished(); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("ClearFinished = %+v", got)
+	}
+	if q.Pending() != 2 {
+		t.Errorf("Pending = %d, want 2", q.Pending())
+	}
+}
+
+func TestQueueMovePrioritize(t *testing.T) {
+	q, _, _ := newTestQueue(t, 1, 1, 1, 1)
+	if !q.Move(3, -1) {
+		t.Fatal("Move(3, -1) failed")
+	}
+	if got := ids(q); !equalInts(got, []int{1, 3, 2, 4}) {
+		t.Errorf("after Move = %v", got)
+	}
+	running, _ := q.Next() // 1
+	if q.Move(3, -1) {
+		t.Error("moved a pending entry above a running one")
+	}
+	if n := q.Prioritize(4, 2, running.ID); n != 2 {
+		t.Errorf("Prioritize moved %d entries, want 2", n)
+	}
+	if got := ids(q); !equalInts(got, []int{1, 2, 4, 3}) {
+		t.Errorf("after Prioritize = %v", got)
+	}
+}
+
+func TestPendingTotalsAccounting(t *testing.T) {
+	q, added, _ := newTestQueue(t, 100, 200, 300)
+	var bp batchProgress
+	bp.Reset()
+	bp.Add(pendingTotals(added))
+	for {
+		e, ok := q.Next()
+		if !ok {
+			break
+		}
+		if e.ID == 2 {
+			bp.Advance(50)
+			q.Finish(e.ID, errors.New("read failed"))
+			bp.FileDone(e.Size - 50)
+			continue
+		}
+		bp.Advance(e.Size)
+		q.Finish(e.ID, nil)
+		bp.FileDone(0)
+	}
+	s := bp.Snapshot()
+	if s.DoneFiles != 3 || s.TotalFiles != 3 || s.DoneBytes != 600 || s.TotalBytes != 600 || s.Percent() != 100 {
+		t.Errorf("snapshot = %+v", s)
+	}
+
+	// 重试失败项时只登记重新排队的部分。
+	retried := q.RetryFailed()
+	bp.Reset()
+	bp.Add(pendingTotals(retried))
+	if s := bp.Snapshot(); s.TotalFiles != 1 || s.TotalBytes != 200 {
+		t.Errorf("retry snapshot = %+v", s)
+	}
+}
+
+// TestQueueMoveFixedEntries 计算中和已结束的条目不能移动，也不能作为交换对象或被提前。
+func TestQueueMoveFixedEntries(t *testing.T) {
+	q, _, notified := newTestQueue(t, 1, 1, 1, 1, 1)
+	done, _ := q.Next() // 1
+	q.Finish(done.ID, nil)
+	failed, _ := q.Next() // 2
+	q.Finish(failed.ID, errors.New("read failed"))
+	running, _ := q.Next() // 3
+	before := *notified
+
+	tests := []struct {
+		name     string
+		id, step int
+	}{
+		{"running up", running.ID, -1},
+		{"running down", running.ID, 1},
+		{"done down", done.ID, 1},
+		{"failed down", failed.ID, 1},
+		{"pending over running", 4, -1},
+		{"pending past end", 5, 1},
+		{"unknown id", 99, -1},
+		{"zero step", 4, 0},
+	}
+	for _, tt := range tests {
+		if q.Move(tt.id, tt.step) {
+			t.Errorf("%s: Move(%d, %d) succeeded", tt.name, tt.id, tt.step)
+		}
+	}
+	if n := q.Prioritize(done.ID, failed.ID, running.ID, 99); n != 0 {
+		t.Errorf("Prioritize of non-pending entries moved %d", n)
+	}
+	if got := ids(q); !equalInts(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("order changed to %v", got)
+	}
+	if *notified != before {
+		t.Errorf("%d notifications for no-op moves", *notified-before)
+	}
+
+	// 等待项只在等待项之间调整，前面已结束和计算中的条目保持原位。
+	if n := q.Prioritize(5, done.ID); n != 1 {
+		t.Errorf("Prioritize(5, done) moved %d, want 1", n)
+	}
+	if got := ids(q); !equalInts(got, []int{1, 2, 3, 5, 4}) {
+		t.Errorf("after Prioritize = %v", got)
+	}
+	if got := states(q); got[0] != jobDone || got[1] != jobFailed || got[2] != jobRunning {
+		t.Errorf("states after Prioritize = %v", got)
+	}
+}
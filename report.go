@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 报告输出：各模式把结果整理成表格（列名 + 行），由同一组格式化器输出为文本、JSON 或 CSV。
+
+type reportFormat int
+
+const (
+	reportText reportFormat = iota
+	reportJSON
+	reportCSV
+)
+
+func parseReportFormat(s string) (reportFormat, error) {
+	switch strings.ToLower(s) {
+	case "text", "txt":
+		return reportText, nil
+	case "json":
+		return reportJSON, nil
+	case "csv":
+		return reportCSV, nil
+	}
+	return 0, errors.New(tr("err.reportFormat", s))
+}
+
+type reportCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type report struct {
+	Columns []string
+	Rows    [][]string
+	Summary []reportCount
+}
+
+func (r *report) add(row ...string) {
+	r.Rows = append(r.Rows, row)
+}
+
+func writeReport(w io.Writer, f reportFormat, r report) error {
+	switch f {
+	case reportJSON:
+		return writeReportJSON(w, r)
+	case reportCSV:
+		return writeReportCSV(w, r)
+	}
+	return writeReportText(w, r)
+}
+
+// writeReportText 每行一条，各列以两个空格分隔；省略末尾的空列，中间的空列显示为 "-"，最后输出汇总。
+func writeReportText(w io.Writer, r report) error {
+	for _, row := range r.Rows {
+		n := len(row)
+		for n > 0 && row[n-1] == "" {
+			n--
+		}
+		cells := make([]string, n)
+		for i, c := range row[:n] {
+			if c == "" {
+				c = "-"
+			}
+			cells[i] = c
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(cells, "  ")); err != nil {
+			return err
+		}
+	}
+	if len(r.Summary) == 0 {
+		return nil
+	}
+	parts := make([]string, len(r.Summary))
+	for i, c := range r.Summary {
+		parts[i] = fmt.Sprintf("%s %d", c.Name, c.Count)
+	}
+	if len(r.Rows) > 0 {
+		fmt.Fprintln(w)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(parts, ", "))
+	return err
+}
+
+func writeReportJSON(w io.Writer, r report) error {
+	rows := make([]map[string]string, len(r.Rows))
+	for i, row := range r.Rows {
+		m := make(map[string]string, len(r.Columns))
+		for j, col := range r.Columns {
+			if j < len(row) && row[j] != "" {
+				m[col] = row[j]
+			}
+		}
+		rows[i] = m
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Rows    []map[string]string `json:"rows"`
+		Summary []reportCount       `json:"summary,omitempty"`
+	}{rows, r.Summary})
+}
+
+func writeReportCSV(w io.Writer, r report) error {
+	cw := csv.NewWriter(w)
+	cw.Write(r.Columns)
+	for _, row := range r.Rows {
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
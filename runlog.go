@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// 运行日志：用 log/slog 记录批次开始/结束、每个文件的结果、跳过与错误，写入按大小滚动的文件。
+// 界面、命令行和服务共用；默认值取自环境变量 SM3HASH_LOG_FILE、SM3HASH_LOG_FORMAT、SM3HASH_LOG_LEVEL，
+// 命令行和服务模式可用 -log-file 等选项覆盖。未指定文件时不记录。
+
+type runLogConfig struct {
+	Path   string
+	Format string // text 或 json
+	Level  string // debug、info、warn、error
+	MaxMB  int64
+	Keep   int
+}
+
+func runLogConfigFromEnv() runLogConfig {
+	c := runLogConfig{Path: os.Getenv("SM3HASH_LOG_FILE"), Format: "text", Level: "info", MaxMB: 10, Keep: 5}
+	if v := os.Getenv("SM3HASH_LOG_FORMAT"); v != "" {
+		c.Format = v
+	}
+	if v := os.Getenv("SM3HASH_LOG_LEVEL"); v != "" {
+		c.Level = v
+	}
+	return c
+}
+
+func (c *runLogConfig) addFlags(flags *flag.FlagSet) {
+	flags.StringVar(&c.Path, "log-file", c.Path, tr("flag.logFile"))
+	flags.StringVar(&c.Format, "log-format", c.Format, tr("flag.logFormat"))
+	flags.StringVar(&c.Level, "log-level", c.Level, tr("flag.logLevel"))
+	flags.Int64Var(&c.MaxMB, "log-max-mb", c.MaxMB, tr("flag.logMaxMB"))
+	flags.IntVar(&c.Keep, "log-keep", c.Keep, tr("flag.logKeep"))
+}
+
+// runLog 在 openRunLog 之前丢弃所有记录。
+var (
+	runLog     = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(100)}))
+	runLogFile *rotatingFile
+)
+
+// openRunLog 按配置替换 runLog；Path 为空时什么也不做。
+func openRunLog(c runLogConfig) error {
+	if c.Path == "" {
+		return nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return errors.New(tr("err.logLevel", c.Level))
+	}
+	format := strings.ToLower(c.Format)
+	if format != "text" && format != "json" {
+		return errors.New(tr("err.logFormat", c.Format))
+	}
+	f, err := openRotatingFile(c.Path, c.MaxMB<<20, c.Keep)
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		runLog = slog.New(slog.NewJSONHandler(f, opts))
+	} else {
+		runLog = slog.New(slog.NewTextHandler(f, opts))
+	}
+	runLogFile = f
+	return nil
+}
+
+func closeRunLog() {
+	if runLogFile != nil {
+		runLogFile.Close()
+	}
+}
+
+// runLogger 返回带 mode（gui、cli、serve、watch）等公共字段的记录器。
+func runLogger(mode string, args ...any) *slog.Logger {
+	return runLog.With(append([]any{"mode", mode}, args...)...)
+}
+
+func logRunStart(l *slog.Logger, files int, bytes int64) {
+	l.Info("run start", "files", files, "bytes", bytes)
+}
+
+func logRunFinish(l *slog.Logger, sum runSummary, d time.Duration) {
+	level := slog.LevelInfo
+	if sum.Failed > 0 || sum.Skipped > 0 {
+		level = slog.LevelWarn
+	}
+	l.Log(context.Background(), level, "run finish",
+		"ok", sum.OK, "cached", sum.Cached, "failed", sum.Failed, "skipped", sum.Skipped, "missing", sum.Missing, "seconds", d.Seconds())
+}
+
+// logFileResult 成功记为 info，失败记为 error 并带上错误分类。
+func logFileResult(l *slog.Logger, path string, size int64, digest string, d time.Duration, cached bool, err error) {
+	if err != nil {
+		l.Error("file failed", "path", path, "size", size, "kind", errKindLabel[classifyError(err)], "err", err.Error(), "seconds", d.Seconds())
+		return
+	}
+	l.Info("file", "path", path, "size", size, "sm3", digest, "cached", cached, "seconds", d.Seconds())
+}
+
+func logSkipped(l *slog.Logger, skipped []error) {
+	for _, err := range skipped {
+		l.Warn("skipped", "kind", errKindLabel[classifyError(err)], "err", err.Error())
+	}
+}
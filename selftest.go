@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 已知答案自检（KAT）：启动时先用 GM/T 0004 附录 A 的两个示例以及 HMAC-SM3、SM3-KDF 向量检查压缩函数，
+// 任一向量不符则拒绝计算摘要（命令行退出码 exitSelfTest，界面提示后退出）。sm3hash selftest 输出逐项结果。
+// 向量能否发现出错的压缩函数由 selftest_test.go 的故障注入测试检查。
+
+// exitSelfTest 是自检未通过时的退出码，与 baseline 的退出码（0～5）互不重叠。
+const exitSelfTest = 6
+
+type katVector struct {
+	Name string
+	run  func(compress sm3BlockFunc) []byte
+	Want string // 十六进制
+}
+
+func katSM3(msg []byte) func(sm3BlockFunc) []byte {
+	return func(compress sm3BlockFunc) []byte {
+		d := newSM3DigestWith(compress)
+		d.Write(msg)
+		return d.Sum(nil)
+	}
+}
+
+func katHMAC(key, msg []byte) func(sm3BlockFunc) []byte {
+	return func(compress sm3BlockFunc) []byte {
+		m := hmac.New(func() hash.Hash { return newSM3DigestWith(compress) }, key)
+		m.Write(msg)
+		return m.Sum(nil)
+	}
+}
+
+func katKDF(z []byte, klen int) func(sm3BlockFunc) []byte {
+	return func(compress sm3BlockFunc) []byte { return sm3KDFWith(compress, z, klen) }
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// katVectors 中 HMAC 与 KDF 的期望值已用独立实现（OpenSSL 的 SM3）交叉核对。
+var katVectors = []katVector{
+	{"sm3 abc", katSM3([]byte("abc")),
+		"66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{"sm3 abcd*16 (512 bit)", katSM3(bytes.Repeat([]byte("abcd"), 16)),
+		"debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	{"sm3 empty", katSM3(nil),
+		"1ab21d8355cfa17f8e61194831e81a8f22bec8c728fefb747ed035eb5082aa2b"},
+	{"hmac-sm3 32-byte key", katHMAC(mustHex("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"),
+		[]byte("abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq")),
+		"be190a66f210be0df56c243d5c1a53e9301fd3f0313244a412f6ae97ab7fe407"},
+	{"hmac-sm3 short key", katHMAC([]byte("Jefe"), []byte("what do ya want for nothing?")),
+		"2e87f1d16862e6d964b50a5200bf2b10b764faa9680a296a2405f24bec39f882"},
+	{"hmac-sm3 80-byte key", katHMAC(bytes.Repeat([]byte{0xaa}, 80), []byte("Test Using Larger Than Block-Size Key - Hash Key First")),
+		"c794651f5455f80546855f744ff50146d5286e1cb677d5088c059cd8b03bb9ce"},
+	{"sm3-kdf 19 bytes", katKDF(mustHex("57e7b63623fae5f08cda468e872a20afa03ded41bf1403770e040dc83af31a67991f2b01ebf9efd8881f0a0493000603"), 19),
+		"046b04a9adf53b389b9e2aafb47d90f4d08978"},
+	{"sm3-kdf 80 bytes", katKDF([]byte("abc"), 80),
+		"fe1ea80dac6f100c33537bd24619ec7c72a1e8b1ffeaefb1eb52a37791fdaf619db16c0ac7bebb47238c6cc925ff66af7936e278e12d2664502bb38b03fd41cb2975a660d33ecc32fe62f27c738964e2"},
+}
+
+type katResult struct {
+	Name string `json:"name"`
+	OK   bool   `json:"ok"`
+	Got  string `json:"got"`
+	Want string `json:"want"`
+}
+
+// runKAT 用 compress 计算全部向量；压缩函数 panic 也记为失败，而不是让进程崩溃。
+func runKAT(compress sm3BlockFunc) []katResult {
+	results := make([]katResult, 0, len(katVectors))
+	for _, v := range katVectors {
+		r := katResult{Name: v.Name, Want: v.Want}
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					r.Got = fmt.Sprintf("panic: %v", p)
+				}
+			}()
+			r.Got = hex.EncodeToString(v.run(compress))
+		}()
+		r.OK = r.Got == r.Want
+		results = append(results, r)
+	}
+	return results
+}
+
+// katError 汇总未通过的向量；全部通过时返回 nil。
+func katError(results []katResult) error {
+	var failed []string
+	for _, r := range results {
+		if !r.OK {
+			failed = append(failed, r.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.New(tr("err.selfTest", strings.Join(failed, ", ")))
+}
+
+var startupSelfTest struct {
+	once sync.Once
+	err  error
+}
+
+// checkSelfTest 在进程内只运行一次自检，之后返回缓存的结果。
+func checkSelfTest() error {
+	startupSelfTest.once.Do(func() {
+		startupSelfTest.err = katError(runKAT(sm3Compress))
+	})
+	return startupSelfTest.err
+}
+
+type selfTestReport struct {
+	Vectors     []katResult `json:"vectors"`
+	Conformance []katResult `json:"conformance,omitempty"`
+	OK          bool        `json:"ok"`
+}
+
+func runSelfTestCommand(args []string) int {
+	flags := flag.NewFlagSet("sm3hash selftest", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, tr("flag.json"))
+	full := flags.Bool("full", false, tr("flag.selfTestFull"))
+	var conf conformanceOptions
+	flags.BoolVar(&conf.Large, "large", false, tr("flag.selfTestLarge"))
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("selftest.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	rep := selfTestReport{Vectors: runKAT(sm3Compress)}
+	if *full {
+		rep.Conformance = runConformance(conf)
+	}
+	rep.OK = katError(rep.Vectors) == nil && katError(rep.Conformance) == nil
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.writeReport", err))
+			return 1
+		}
+	} else {
+		for _, r := range append(rep.Vectors, rep.Conformance...) {
+			status := "PASS"
+			if !r.OK {
+				status = "FAIL"
+			}
+			fmt.Printf("%-4s  %s\n", status, r.Name)
+			if !r.OK {
+				fmt.Printf("      got  %s\n      want %s\n", r.Got, r.Want)
+			}
+		}
+	}
+	if !rep.OK {
+		return exitSelfTest
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKnownAnswers(t *testing.T) {
+	for _, r := range runKAT(sm3Compress) {
+		if !r.OK {
+			t.Errorf("%s: got %s, want %s", r.Name, r.Got, r.Want)
+		}
+	}
+	if err := checkSelfTest(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSelfTestDetectsFaults 换入故意出错的压缩函数，确认自检确实能发现错误。
+func TestSelfTestDetectsFaults(t *testing.T) {
+	faults := []struct {
+		name     string
+		compress sm3BlockFunc
+		every    bool // 每个向量都应发现
+	}{
+		// 第一个字出错会影响所有输出，包括截短的 KDF 输出。
+		{"flip v[0]", func(v *[8]uint32, b []byte) { sm3Compress(v, b); v[0] ^= 1 }, true},
+		{"flip v[7]", func(v *[8]uint32, b []byte) { sm3Compress(v, b); v[7] ^= 0x80000000 }, false},
+		{"skip block", func(v *[8]uint32, b []byte) {}, true},
+		{"no feed-forward", func(v *[8]uint32, b []byte) {
+			orig := *v
+			sm3Compress(v, b)
+			for i := range v {
+				v[i] ^= orig[i]
+			}
+		}, true},
+		{"panic", func(v *[8]uint32, b []byte) { panic("broken") }, true},
+	}
+	for _, f := range faults {
+		t.Run(f.name, func(t *testing.T) {
+			results := runKAT(f.compress)
+			err := katError(results)
+			if err == nil {
+				t.Fatal("self-test passed with a faulty compression function")
+			}
+			for _, r := range results {
+				if f.every && r.OK {
+					t.Errorf("%s did not detect the fault", r.Name)
+				}
+				if !r.OK && !strings.Contains(err.Error(), r.Name) {
+					t.Errorf("error %q does not name %s", err, r.Name)
+				}
+			}
+		})
+	}
+}
+
+// TestSelfTestJSONWriteError 标准输出无法写入时 -json 不能报告成功。
+func TestSelfTestJSONWriteError(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "out")
+	if err := os.WriteFile(p, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ro, err := os.Open(p) // 只读打开，写入必然失败
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = ro, ro
+	code := runSelfTestCommand([]string{"-json"})
+	os.Stdout, os.Stderr = stdout, stderr
+	if code == 0 {
+		t.Error("selftest -json exited 0 although the report could not be written")
+	}
+	if code := runQuiet(t, runSelfTestCommand, "-json"); code != 0 {
+		t.Errorf("selftest -json exit code %d, want 0", code)
+	}
+}
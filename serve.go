@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本地 HTTP 服务：请求体以流的方式送入 SM3 / HMAC-SM3，不落盘，返回 JSON。
+//
+//	POST /v1/sm3                         计算摘要
+//	POST /v1/verify?expected=摘要         计算并与期望值比较（也可用 X-SM3-Expected 头）
+//	POST /v1/hmac                        计算 HMAC-SM3，十六进制密钥放在 X-HMAC-Key 头
+//	POST /v1/hmac/verify?expected=…      计算并比较 HMAC-SM3
+//
+// 密钥只从请求头读取：查询参数会留在访问日志、代理日志和命令历史里，带 key= 的请求直接拒绝。
+//	GET  /v1/health
+//	GET  /metrics                        Prometheus 格式的指标，见 metrics.go
+//	/v1/jobs…                            服务端路径任务，见 jobs.go（需 -allow-root）
+
+type serveConfig struct {
+	Addr         string
+	MaxBody      int64 // 单个请求体的最大字节数
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	AllowRoots   []string // 路径任务可以读取的目录，为空时禁用 /v1/jobs
+	MaxJobs      int      // 同时运行的路径任务数
+	IO           ioOptions
+}
+
+var defaultServeConfig = serveConfig{
+	Addr:         "127.0.0.1:8341",
+	MaxBody:      1 << 30,
+	ReadTimeout:  10 * time.Minute,
+	WriteTimeout: 10 * time.Minute,
+	IdleTimeout:  2 * time.Minute,
+	MaxJobs:      2,
+}
+
+type hashResponse struct {
+	SM3      string  `json:"sm3,omitempty"`
+	HMAC     string  `json:"hmac,omitempty"`
+	Base64   string  `json:"base64"`
+	Size     int64   `json:"size"`
+	Seconds  float64 `json:"seconds"`
+	Match    *bool   `json:"match,omitempty"`
+	Expected string  `json:"expected,omitempty"`
+}
+
+// errorResponse 的 code 是稳定的英文错误码，供程序判断；error 按 Accept-Language 本地化，供人阅读。
+type errorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// requestLanguage 按 Accept-Language 中权重最高的已支持语言选择消息语言，都不支持时使用服务端的语言。
+func requestLanguage(r *http.Request) language {
+	best, bestQ := currentLang, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if l, ok := languageTag(tag); ok && q > bestQ {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+// writeError 写出错误码与按请求语言翻译的消息 key。
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, key string, args ...any) {
+	writeJSON(w, status, errorResponse{Code: code, Error: trLang(requestLanguage(r), key, args...)})
+}
+
+// sm3Server 处理 /v1/ 下的请求。
+type sm3Server struct {
+	cfg  serveConfig
+	mux  *http.ServeMux
+	jobs *jobManager
+}
+
+func newSM3Server(cfg serveConfig) *sm3Server {
+	s := &sm3Server{cfg: cfg, mux: http.NewServeMux(), jobs: newJobManager(cfg.AllowRoots, cfg.MaxJobs, cfg.IO)}
+	s.mux.HandleFunc("/v1/sm3", s.post(s.handleHash(false, false)))
+	s.mux.HandleFunc("/v1/verify", s.post(s.handleHash(false, true)))
+	s.mux.HandleFunc("/v1/hmac", s.post(s.handleHash(true, false)))
+	s.mux.HandleFunc("/v1/hmac/verify", s.post(s.handleHash(true, true)))
+	s.mux.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		hashMetrics.write(w)
+	})
+	s.mux.Handle("/v1/jobs", s.jobs)
+	s.mux.Handle("/v1/jobs/", s.jobs)
+	return s
+}
+
+func (s *sm3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *sm3Server) post(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "api.methodPost")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// param 先取查询参数，再取请求头。
+func param(r *http.Request, query, header string) string {
+	if v := r.URL.Query().Get(query); v != "" {
+		return v
+	}
+	return r.Header.Get(header)
+}
+
+func (s *sm3Server) handleHash(useHMAC, verify bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var h hash.Hash = newSM3Digest()
+		if useHMAC {
+			if r.URL.Query().Has("key") {
+				writeError(w, r, http.StatusBadRequest, "key_in_query", "api.keyInQuery")
+				return
+			}
+			key, err := hex.DecodeString(r.Header.Get("X-HMAC-Key"))
+			if err != nil || len(key) == 0 {
+				writeError(w, r, http.StatusBadRequest, "invalid_key", "api.badKey")
+				return
+			}
+			h = hmac.New(newSM3, key)
+		}
+		var want []byte
+		if verify {
+			expected := param(r, "expected", "X-SM3-Expected")
+			var err error
+			if want, err = decodeDigestValue(expected); err != nil {
+				writeError(w, r, http.StatusBadRequest, "invalid_expected", "api.badExpected", expected)
+				return
+			}
+		}
+
+		start := time.Now()
+		body := http.MaxBytesReader(w, r.Body, s.cfg.MaxBody)
+		n, err := io.Copy(h, body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "api.bodyTooLarge", s.cfg.MaxBody)
+				return
+			}
+			writeError(w, r, http.StatusBadRequest, "body_read_failed", "api.badBody", err)
+			return
+		}
+		hashMetrics.observeStream(n)
+		runLogger("serve").Debug("stream", "path", r.URL.Path, "size", n, "seconds", time.Since(start).Seconds())
+		sum := h.Sum(nil)
+		resp := hashResponse{Base64: base64.StdEncoding.EncodeToString(sum), Size: n, Seconds: time.Since(start).Seconds()}
+		if useHMAC {
+			resp.HMAC = hex.EncodeToString(sum)
+		} else {
+			resp.SM3 = hex.EncodeToString(sum)
+		}
+		if verify {
+			ok := hmac.Equal(sum, want)
+			resp.Match = &ok
+			resp.Expected = hex.EncodeToString(want)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// decodeDigestValue 按 parseDigest 支持的任意编码解析 32 字节的摘要或 HMAC 值。
+func decodeDigestValue(s string) ([]byte, error) {
+	d, err := parseDigest(s)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(d)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newHTTPServer 配置超时；ReadHeaderTimeout 防止慢速请求头占用连接。
+func newHTTPServer(cfg serveConfig, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    64 << 10,
+	}
+}
+
+// runServe 实现 "sm3hash serve"，按 Ctrl+C 停止并等待进行中的请求结束。
+func runServe(args []string) int {
+	cfg := defaultServeConfig
+	flags := flag.NewFlagSet("sm3hash serve", flag.ContinueOnError)
+	flags.StringVar(&cfg.Addr, "addr", cfg.Addr, tr("flag.addr"))
+	maxMB := flags.Int64("max-body-mb", cfg.MaxBody>>20, tr("flag.maxBodyMB"))
+	flags.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, tr("flag.readTimeout"))
+	flags.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, tr("flag.writeTimeout"))
+	flags.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, tr("flag.idleTimeout"))
+	flags.Func("allow-root", tr("flag.allowRoot"), func(v string) error {
+		cfg.AllowRoots = append(cfg.AllowRoots, v)
+		return nil
+	})
+	flags.IntVar(&cfg.MaxJobs, "max-jobs", cfg.MaxJobs, tr("flag.maxJobs"))
+	cfg.IO.addFlags(flags)
+	logCfg := runLogConfigFromEnv()
+	logCfg.addFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("serve.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	cfg.MaxBody = *maxMB << 20
+	for _, root := range cfg.AllowRoots {
+		if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.allowRoot", root))
+			return 2
+		}
+	}
+	if err := openRunLog(logCfg); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.runLog", err))
+		return 2
+	}
+	defer closeRunLog()
+	rl := runLogger("serve")
+
+	srv := newHTTPServer(cfg, newSM3Server(cfg))
+	done := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		close(done)
+	}()
+	fmt.Fprintln(os.Stderr, tr("serve.listening", cfg.Addr))
+	rl.Info("server start", "addr", cfg.Addr, "allow_roots", cfg.AllowRoots)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		rl.Error("server failed", "err", err.Error())
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 1
+	}
+	<-done
+	rl.Info("server stop")
+	return 0
+}
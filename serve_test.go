@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveRequest(t *testing.T, h http.Handler, method, target, body string, header http.Header) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: response %q is not JSON: %v", method, target, rec.Body, err)
+	}
+	return rec, out
+}
+
+func TestServeHashEndpoints(t *testing.T) {
+	abc := katVectors[0].Want
+	jefe := "2e87f1d16862e6d964b50a5200bf2b10b764faa9680a296a2405f24bec39f882"
+	jefeKey := hex.EncodeToString([]byte("Jefe"))
+	body := "what do ya want for nothing?"
+	cfg := defaultServeConfig
+	cfg.MaxBody = int64(len(body))
+	srv := newSM3Server(cfg)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		header   http.Header
+		status   int
+		want     map[string]any // 响应中必须出现的字段
+		wantCode string
+	}{
+		{"sm3", "POST", "/v1/sm3", "abc", nil, 200, map[string]any{"sm3": abc, "size": 3.0}, ""},
+		{"verify match", "POST", "/v1/verify?expected=" + abc, "abc", nil, 200, map[string]any{"match": true, "expected": abc}, ""},
+		{"verify header", "POST", "/v1/verify", "abc", http.Header{"X-Sm3-Expected": {strings.ToUpper(abc)}}, 200, map[string]any{"match": true}, ""},
+		{"verify mismatch", "POST", "/v1/verify?expected=" + katVectors[2].Want, "abc", nil, 200, map[string]any{"match": false}, ""},
+		{"verify bad digest", "POST", "/v1/verify?expected=zz", "abc", nil, 400, nil, "invalid_expected"},
+		{"verify missing digest", "POST", "/v1/verify", "abc", nil, 400, nil, "invalid_expected"},
+		{"hmac", "POST", "/v1/hmac", body, http.Header{"X-Hmac-Key": {jefeKey}}, 200, map[string]any{"hmac": jefe}, ""},
+		{"hmac verify", "POST", "/v1/hmac/verify?expected=" + jefe, body, http.Header{"X-Hmac-Key": {jefeKey}}, 200, map[string]any{"match": true}, ""},
+		{"hmac bad key", "POST", "/v1/hmac", body, http.Header{"X-Hmac-Key": {"xyz"}}, 400, nil, "invalid_key"},
+		{"hmac key in query", "POST", "/v1/hmac?key=" + jefeKey, body, nil, 400, nil, "key_in_query"},
+		{"hmac key in query and header", "POST", "/v1/hmac/verify?key=" + jefeKey + "&expected=" + jefe, body, http.Header{"X-Hmac-Key": {jefeKey}}, 400, nil, "key_in_query"},
+		{"hmac empty key in query", "POST", "/v1/hmac?key=", body, http.Header{"X-Hmac-Key": {jefeKey}}, 400, nil, "key_in_query"},
+		{"hmac missing key", "POST", "/v1/hmac/verify?expected=" + jefe, body, nil, 400, nil, "invalid_key"},
+		{"oversized", "POST", "/v1/sm3", body + "!", nil, 413, nil, "body_too_large"},
+		{"wrong method", "GET", "/v1/sm3", "", nil, 405, nil, "method_not_allowed"},
+		{"wrong method hmac", "PUT", "/v1/hmac/verify", "", nil, 405, nil, "method_not_allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, out := serveRequest(t, srv, tt.method, tt.target, tt.body, tt.header)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%v)", rec.Code, tt.status, out)
+			}
+			for k, v := range tt.want {
+				if out[k] != v {
+					t.Errorf("%s = %v, want %v", k, out[k], v)
+				}
+			}
+			if tt.wantCode != "" && (out["code"] != tt.wantCode || out["error"] == "") {
+				t.Errorf("error response = %v, want code %s", out, tt.wantCode)
+			}
+			if tt.status == 405 && rec.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("Allow = %q", rec.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestServeErrorLanguage(t *testing.T) {
+	srv := newSM3Server(defaultServeConfig)
+	tests := []struct {
+		accept string
+		want   language
+	}{
+		{"en-US,en;q=0.9", langEN},
+		{"zh-CN", langZH},
+		{"fr-FR, en;q=0.5, zh;q=0.8", langZH},
+		{"de", currentLang},
+		{"", currentLang},
+	}
+	for _, tt := range tests {
+		_, out := serveRequest(t, srv, "GET", "/v1/sm3", "", http.Header{"Accept-Language": {tt.accept}})
+		if want := trLang(tt.want, "api.methodPost"); out["error"] != want || out["code"] != "method_not_allowed" {
+			t.Errorf("Accept-Language %q: %v, want error %q", tt.accept, out, want)
+		}
+	}
+}
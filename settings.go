@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// 界面的用户设置：保存在 os.UserConfigDir()/SM3Hash/settings.json，启动时读取，修改后与退出时保存。
+// 读写与迁移不依赖 Win32，便于在任何平台上检查。
+
+// settingsVersion 是当前的设置文件版本；读到旧版本时由 migrateSettings 逐级升级。
+const settingsVersion = 1
+
+type windowRect struct {
+	X         int  `json:"x"`
+	Y         int  `json:"y"`
+	Width     int  `json:"width"`
+	Height    int  `json:"height"`
+	Maximized bool `json:"maximized,omitempty"`
+}
+
+type settings struct {
+	Version      int        `json:"version"`
+	ShowSize     bool       `json:"show_size"`
+	ShowTime     bool       `json:"show_time"`
+	Upper        bool       `json:"upper"`
+	DenyWrite    bool       `json:"deny_write"`
+	UseCache     bool       `json:"use_cache"`
+	Format       string     `json:"format"`              // digestFormats 中的名字
+	TextEncoding string     `json:"text_encoding"`       // 文本行编码下拉框中的选项
+	Language     string     `json:"language,omitempty"`  // zh、en，空串表示按系统区域
+	IO           string     `json:"io,omitempty"`        // 读取策略，见 ioStrategyNames；界面中没有对应控件，需手工编辑
+	BufferKB     int        `json:"buffer_kb,omitempty"` // 读取缓冲区大小，0 表示默认
+	Window       windowRect `json:"window"`
+	LastOpenDir  string     `json:"last_open_dir,omitempty"`
+	LastSaveDir  string     `json:"last_save_dir,omitempty"`
+	LastWatchDir string     `json:"last_watch_dir,omitempty"`
+}
+
+const (
+	minWindowWidth  = 480
+	minWindowHeight = 420
+)
+
+var defaultSettings = settings{
+	Version:      settingsVersion,
+	ShowSize:     true,
+	ShowTime:     true,
+	Upper:        true,
+	UseCache:     true,
+	Format:       "hex",
+	TextEncoding: "UTF-8",
+	Window:       windowRect{X: 120, Y: 120, Width: 620, Height: 600},
+}
+
+func defaultSettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "SM3Hash", "settings.json"), nil
+}
+
+// loadSettings 读取设置；文件不存在时返回默认值。
+// 无法解析的文件改名为 .bad 后同样返回默认值，并把原因作为错误返回，调用方可以只提示不退出。
+func loadSettings(path string) (settings, error) {
+	s := defaultSettings
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	// 先解码到默认值之上，旧文件中没有的字段保持默认。
+	if err := json.Unmarshal(data, &s); err != nil {
+		os.Rename(path, path+".bad")
+		return defaultSettings, fmt.Errorf("%s: %w", tr("err.settingsCorrupt", filepath.Base(path)), err)
+	}
+	err = migrateSettings(&s)
+	s.normalize()
+	return s, err
+}
+
+// errSettingsTooNew 表示设置文件来自更新的版本：能识别的字段照常使用，但本次不应写回，以免丢掉新字段。
+var errSettingsTooNew = localizedError("err.settingsTooNew")
+
+// settingsSavePath 根据读取结果决定之后写回设置的路径；设置文件来自更新的版本时返回空串，本次运行不保存。
+func settingsSavePath(path string, loadErr error) string {
+	if errors.Is(loadErr, errSettingsTooNew) {
+		return ""
+	}
+	return path
+}
+
+// migrateSettings 把旧版本的设置逐级升级到 settingsVersion。没有 version 字段的文件视为第 1 版。
+func migrateSettings(s *settings) error {
+	if s.Version == 0 {
+		s.Version = 1
+	}
+	if s.Version > settingsVersion {
+		return errSettingsTooNew
+	}
+	// 以后的升级在这里按版本追加，例如：
+	//	if s.Version == 1 { …; s.Version = 2 }
+	s.Version = settingsVersion
+	return nil
+}
+
+// normalize 修正超出范围的值，避免窗口过小或引用不存在的选项。
+func (s *settings) normalize() {
+	if _, err := parseDigestFormat(s.Format); err != nil {
+		s.Format = defaultSettings.Format
+	}
+	if _, err := parseIOStrategy(s.IO); err != nil {
+		s.IO = ""
+	}
+	if s.BufferKB < 0 {
+		s.BufferKB = 0
+	}
+	if s.Window.Width < minWindowWidth {
+		s.Window.Width = defaultSettings.Window.Width
+	}
+	if s.Window.Height < minWindowHeight {
+		s.Window.Height = defaultSettings.Window.Height
+	}
+}
+
+// ioOptions 返回设置中的读取策略；未设置时为 plain。
+func (s settings) ioOptions() ioOptions {
+	st, _ := parseIOStrategy(s.IO)
+	return ioOptions{Strategy: st, BufferKB: s.BufferKB}
+}
+
+// saveSettings 先写临时文件再改名，写到一半退出也不会留下损坏的设置。
+func saveSettings(path string, s settings) error {
+	s.Version = settingsVersion
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".settings-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
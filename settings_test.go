@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  *string // nil 表示文件不存在
+		want     func(settings) bool
+		wantErr  error // 期望 errors.Is 匹配；anyErr 表示任意错误
+		bad      bool  // 原文件应改名为 .bad
+		writable bool  // settingsSavePath 应返回原路径
+	}{
+		{"missing file", nil, func(s settings) bool { return s == defaultSettings }, nil, false, true},
+		{"corrupt file", strPtr(`{"show_size": tru`), func(s settings) bool { return s == defaultSettings }, anyErr, true, true},
+		{"version 0", strPtr(`{"upper": false, "format": "base64"}`), func(s settings) bool {
+			return s.Version == settingsVersion && !s.Upper && s.Format == "base64" && s.ShowSize && s.TextEncoding == "UTF-8"
+		}, nil, false, true},
+		{"too new", strPtr(`{"version": 99, "upper": false, "future_field": 1}`), func(s settings) bool {
+			return s.Version == 99 && !s.Upper
+		}, errSettingsTooNew, false, false},
+		{"clamped", strPtr(`{"version": 1, "window": {"x": 5, "y": 6, "width": 10, "height": -3}, "format": "md5", "io": "fast", "buffer_kb": -1}`), func(s settings) bool {
+			return s.Window == windowRect{X: 5, Y: 6, Width: defaultSettings.Window.Width, Height: defaultSettings.Window.Height} &&
+				s.Format == "hex" && s.IO == "" && s.BufferKB == 0
+		}, nil, false, true},
+		{"in range", strPtr(`{"version": 1, "window": {"x": 1, "y": 2, "width": 800, "height": 700, "maximized": true}, "io": "mmap", "buffer_kb": 64}`), func(s settings) bool {
+			return s.Window == windowRect{X: 1, Y: 2, Width: 800, Height: 700, Maximized: true} &&
+				s.ioOptions() == ioOptions{Strategy: ioMmap, BufferKB: 64}
+		}, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings.json")
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s, err := loadSettings(path)
+			switch {
+			case tt.wantErr == anyErr && err == nil, tt.wantErr == nil && err != nil,
+				tt.wantErr != nil && tt.wantErr != anyErr && !errors.Is(err, tt.wantErr):
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.want(s) {
+				t.Errorf("settings = %+v", s)
+			}
+			_, statBad := os.Stat(path + ".bad")
+			if (statBad == nil) != tt.bad {
+				t.Errorf(".bad exists = %v, want %v", statBad == nil, tt.bad)
+			}
+			if got := settingsSavePath(path, err); (got == path) != tt.writable {
+				t.Errorf("settingsSavePath = %q, writable want %v", got, tt.writable)
+			}
+		})
+	}
+}
+
+var anyErr = errors.New("any error")
+
+func strPtr(s string) *string { return &s }
+
+func TestSaveSettingsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "settings.json")
+	s := defaultSettings
+	s.Version, s.Language, s.LastOpenDir = 0, "en", `C:\data`
+	if err := saveSettings(path, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadSettings(path)
+	s.Version = settingsVersion
+	if err != nil || got != s {
+		t.Errorf("loadSettings = %+v, %v; want %+v", got, err, s)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".settings-*")); len(tmp) != 0 {
+		t.Errorf("temporary files left: %v", tmp)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 校验文件（sidecar）：每个文件旁写一个 file.iso.sm3，或按相对路径写入镜像目录。
+
+// errNoSidecar 表示校验时文件没有对应的校验文件（压缩包成员总是如此）。
+var errNoSidecar error = localizedError("err.noSidecar")
+
+type checksumFormat int
+
+const (
+	formatGNU checksumFormat = iota // "摘要  文件名"，与 sm3sum / sha256sum 一致
+	formatBSD                       // "SM3 (文件名) = 摘要"
+)
+
+func parseChecksumFormat(s string) (checksumFormat, error) {
+	switch strings.ToLower(s) {
+	case "gnu":
+		return formatGNU, nil
+	case "bsd":
+		return formatBSD, nil
+	}
+	return 0, errors.New(tr("err.sidecarFormat", s))
+}
+
+func formatChecksumLine(f checksumFormat, digest, name string) string {
+	name = filepath.ToSlash(name)
+	if f == formatBSD {
+		return fmt.Sprintf("SM3 (%s) = %s\n", name, digest)
+	}
+	return fmt.Sprintf("%s  %s\n", digest, name)
+}
+
+// parseChecksumLine 解析 GNU 或 BSD 格式的一行，GNU 格式的二进制标记 '*' 一并接受；
+// 摘要可以是 parseDigest 支持的任意编码，返回时统一为小写十六进制。
+func parseChecksumLine(line string) (digest, name string, ok bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if rest, found := strings.CutPrefix(line, "SM3 ("); found {
+		i := strings.LastIndex(rest, ") = ")
+		if i < 0 {
+			return "", "", false
+		}
+		digest, name = rest[i+4:], rest[:i]
+	} else {
+		i := strings.IndexAny(line, " \t")
+		if i <= 0 || i+1 >= len(line) {
+			return "", "", false
+		}
+		digest, name = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		name = strings.TrimPrefix(name, "*")
+	}
+	digest, err := parseDigest(digest)
+	if err != nil {
+		return "", "", false
+	}
+	return digest, name, true
+}
+
+// sidecarLayout 描述校验文件的位置：默认与原文件同目录，设置 mirror 时按相对路径放入镜像目录。
+type sidecarLayout struct {
+	ext    string
+	mirror string
+	roots  []string
+}
+
+func (l sidecarLayout) path(file string) string {
+	if l.mirror == "" {
+		return file + l.ext
+	}
+	return filepath.Join(l.mirror, relativeToRoots(file, l.roots)+l.ext)
+}
+
+// writeSidecar 写入十六进制摘要，统一为小写以便 sm3sum 等工具直接校验；-format 只影响屏幕输出。
+func writeSidecar(l sidecarLayout, f checksumFormat, file, hexDigest string) error {
+	dst := l.path(file)
+	if l.mirror != "" {
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(dst, []byte(formatChecksumLine(f, strings.ToLower(hexDigest), filepath.Base(file))), 0644)
+}
+
+// readSidecar 查找文件对应的校验文件，返回其中与文件名匹配的摘要；只有一行时不比较文件名。
+func readSidecar(l sidecarLayout, file string) (string, error) {
+	f, err := os.Open(l.path(file))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	base := filepath.Base(file)
+	var entries [][2]string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if digest, name, ok := parseChecksumLine(sc.Text()); ok {
+			entries = append(entries, [2]string{digest, name})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if filepath.Base(filepath.FromSlash(e[1])) == base {
+			return e[0], nil
+		}
+	}
+	if len(entries) == 1 {
+		return entries[0][0], nil
+	}
+	return "", errors.New(tr("err.sidecarMissing", l.path(file), base))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksumLineRoundTrip(t *testing.T) {
+	digest := sm3OneShot([]byte("abc"))
+	for i := range digestFormats {
+		for _, upper := range []bool{false, true} {
+			for _, cf := range []checksumFormat{formatGNU, formatBSD} {
+				for _, name := range []string{"a.iso", "dir/with space (1).bin"} {
+					line := formatChecksumLine(cf, formatDigest(digest, digestFormat(i), upper), name)
+					got, gotName, ok := parseChecksumLine(line)
+					if !ok || got != digest || gotName != name {
+						t.Errorf("%s upper=%v format=%d: parseChecksumLine(%q) = %q, %q, %v",
+							digestFormat(i), upper, cf, line, got, gotName, ok)
+					}
+				}
+			}
+		}
+	}
+	for _, line := range []string{"", "abc", "SM3 (x) = zz", "nothex  file"} {
+		if _, _, ok := parseChecksumLine(line); ok {
+			t.Errorf("parseChecksumLine(%q) accepted", line)
+		}
+	}
+}
+
+func TestWriteSidecarLowercaseHex(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.bin")
+	digest := sm3OneShot([]byte("abc"))
+	for _, cf := range []checksumFormat{formatGNU, formatBSD} {
+		l := sidecarLayout{ext: ".sm3"}
+		if err := writeSidecar(l, cf, file, strings.ToUpper(digest)); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(l.path(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := formatChecksumLine(cf, digest, "data.bin"); string(b) != want {
+			t.Errorf("sidecar = %q, want %q", b, want)
+		}
+		if got, err := readSidecar(l, file); err != nil || got != digest {
+			t.Errorf("readSidecar = %q, %v", got, err)
+		}
+	}
+}
+
+// runQuiet 运行一个命令行入口（runCLI、runBaseline 等），丢弃输出，返回退出码。
+func runQuiet(t *testing.T, run func([]string) int, args ...string) int {
+	t.Helper()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = null, null
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	return run(args)
+}
+
+// TestSidecarMissingAndArchives 压缩包成员不写校验文件；缺少校验文件默认不算失败，-require-sidecar 时算失败。
+func TestSidecarMissingAndArchives(t *testing.T) {
+	defer closeArchives()
+	dir := t.TempDir()
+	a, b, z := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "z.zip")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zf, err := os.Create(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	w, _ := zw.Create("m")
+	w.Write([]byte("member"))
+	zw.Close()
+	zf.Close()
+
+	if code := runQuiet(t, runCLI, "-no-cache", "-archives", "-sidecar", a, z); code != 0 {
+		t.Fatalf("-sidecar exit code %d", code)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.sm3"))
+	if len(matches) != 1 || matches[0] != a+".sm3" {
+		t.Errorf("sidecars written: %v, want only %s.sm3", matches, a)
+	}
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"-verify-sidecar", a}, 0},
+		{[]string{"-verify-sidecar", a, b}, 0},
+		{[]string{"-verify-sidecar", "-archives", a, z}, 0},
+		{[]string{"-verify-sidecar", "-require-sidecar", a, b}, 1},
+		{[]string{"-verify-sidecar", "-require-sidecar", "-archives", z}, 1},
+	}
+	for _, tt := range tests {
+		if code := runQuiet(t, runCLI, append([]string{"-no-cache"}, tt.args...)...); code != tt.want {
+			t.Errorf("%v: exit code %d, want %d", tt.args, code, tt.want)
+		}
+	}
+
+	var sum runSummary
+	for _, err := range []error{nil, errNoSidecar, errVerifyFailed} {
+		sum.add(err)
+	}
+	if sum.OK != 1 || sum.Missing != 1 || sum.Failed != 1 {
+		t.Errorf("summary = %+v", sum)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// ---- SM3 ----
+var sm3IV = [8]uint32{0x7380166F, 0x4914B2B9, 0x172442D7, 0xDA8A0600, 0xA96F30BC, 0x163138AA, 0xE38DEE4D, 0xB0FB0E4E}
+
+// hashOptions 控制读取文件的方式与稳定性检查。
+type hashOptions struct {
+	Retries   int             // 检测到文件变化后的重试次数
+	DenyWrite bool            // Windows 下以拒绝写共享方式打开，读取期间其他进程无法写入
+	Cancel    <-chan struct{} // 关闭后在下一次读取前返回 errCanceled
+	IO        ioOptions       // 读取策略与缓冲区大小
+}
+
+var defaultHashOptions = hashOptions{Retries: 2}
+
+// progress 按百分比节流回调已读字节数与文件总长度。
+type progressFunc func(done, total int64)
+
+// computeSM3File 计算文件摘要；读取前后大小、修改时间或文件标识不一致时视为不稳定，
+// 按 opt.Retries 重试，仍不稳定则返回 errFileChanged。
+func computeSM3File(path string, opt hashOptions, progress progressFunc) (string, error) {
+	if archive, member, ok := splitArchivePath(path); ok {
+		return computeSM3Member(archive, member, opt.Cancel, progress)
+	}
+	for attempt := 0; ; attempt++ {
+		res, err := computeSM3Once(path, opt, progress)
+		if !errors.Is(err, errFileChanged) || attempt >= opt.Retries {
+			return res, err
+		}
+	}
+}
+
+func computeSM3Once(path string, opt hashOptions, progress progressFunc) (string, error) {
+	f, err := openForHash(path, opt.DenyWrite, opt.IO.Strategy == ioDirect)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	before, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	d := newSM3Digest()
+	total, err := hashFile(d, f, before.Size(), opt, progress)
+	if err != nil {
+		return "", err
+	}
+	if err := checkStable(path, f, before, total); err != nil {
+		return "", err
+	}
+	return sm3ToHex(d.finish()), nil
+}
+
+// cancelReader 在 done 关闭后停止读取；done 为 nil 时不可取消。
+type cancelReader struct {
+	r    io.Reader
+	done <-chan struct{}
+}
+
+func (c cancelReader) Read(p []byte) (int, error) {
+	select {
+	case <-c.done:
+		return 0, errCanceled
+	default:
+	}
+	return c.r.Read(p)
+}
+
+// hashReader 把 r 的全部内容写入 d，并按百分比节流回调进度；length 为预期长度，未知时为 0。
+func hashReader(d *sm3Digest, r io.Reader, length int64, progress progressFunc) (int64, error) {
+	return hashReaderBuf(d, r, length, make([]byte, defaultIOBufferKB<<10), progress)
+}
+
+// hashReaderBuf 与 hashReader 相同，但使用调用方提供的缓冲区，每次读取 len(buf) 字节。
+func hashReaderBuf(d *sm3Digest, r io.Reader, length int64, buf []byte, progress progressFunc) (int64, error) {
+	pt := newProgressThrottle(progress, length)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			d.Write(buf[:n])
+			pt.add(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return pt.total, err
+		}
+	}
+	pt.done()
+	return pt.total, nil
+}
+
+// progressThrottle 累计已处理字节数，百分比变化时才回调；length 未知时只在开始和结束时回调。
+type progressThrottle struct {
+	fn       progressFunc
+	length   int64
+	total    int64
+	lastPct  int
+	lastSend time.Time
+}
+
+func newProgressThrottle(fn progressFunc, length int64) *progressThrottle {
+	if fn != nil {
+		fn(0, length)
+	}
+	return &progressThrottle{fn: fn, length: length, lastPct: -1, lastSend: time.Now()}
+}
+
+func (p *progressThrottle) add(n int) {
+	p.total += int64(n)
+	if p.length <= 0 || p.fn == nil {
+		return
+	}
+	pct := int((p.total * 100) / p.length)
+	if pct > 100 {
+		pct = 100
+	}
+	if pct != p.lastPct && (pct-p.lastPct >= 1 || time.Since(p.lastSend) > 200*time.Millisecond) {
+		p.lastPct = pct
+		p.lastSend = time.Now()
+		p.fn(p.total, p.length)
+	}
+}
+
+func (p *progressThrottle) done() {
+	if p.fn != nil {
+		p.fn(p.total, p.length)
+	}
+}
+
+// checkStable 比较读取前后打开的句柄和路径指向的文件，确认内容未被改写或替换。
+func checkStable(path string, f *os.File, before fs.FileInfo, read int64) error {
+	after, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	cur, err := os.Stat(path)
+	changed := err != nil ||
+		(before.Mode().IsRegular() && read != before.Size()) ||
+		after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) ||
+		cur.Size() != before.Size() || !cur.ModTime().Equal(before.ModTime()) ||
+		!os.SameFile(before, cur)
+	if changed {
+		return &fs.PathError{Op: "read", Path: path, Err: errFileChanged}
+	}
+	return nil
+}
+
+// sm3Digest 是流式 SM3，实现 hash.Hash，供文件、压缩包成员和文本输入共用。
+type sm3Digest struct {
+	v        [8]uint32
+	block    [64]byte
+	bufLen   int
+	total    uint64
+	compress sm3BlockFunc
+}
+
+// sm3BlockFunc 是压缩函数的签名；自检可以换入其他实现，确认能发现错误结果。
+type sm3BlockFunc func(v *[8]uint32, block []byte)
+
+func newSM3Digest() *sm3Digest { return newSM3DigestWith(sm3Compress) }
+
+func newSM3DigestWith(compress sm3BlockFunc) *sm3Digest {
+	d := &sm3Digest{compress: compress}
+	d.Reset()
+	return d
+}
+
+func newSM3() hash.Hash { return newSM3Digest() }
+
+// hmacSM3 计算 HMAC-SM3（RFC 2104，分组长度 64 字节）。
+func hmacSM3(key, msg []byte) []byte {
+	m := hmac.New(newSM3, key)
+	m.Write(msg)
+	return m.Sum(nil)
+}
+
+// sm3KDF 是 GM/T 0003 中基于 SM3 的密钥派生函数：依次计算 SM3(z || ct)，ct 从 1 开始按 32 位大端递增，
+// 拼接后截取前 klen 字节。
+func sm3KDF(z []byte, klen int) []byte { return sm3KDFWith(sm3Compress, z, klen) }
+
+func sm3KDFWith(compress sm3BlockFunc, z []byte, klen int) []byte {
+	out := make([]byte, 0, klen+32)
+	d := newSM3DigestWith(compress)
+	for ct := uint32(1); len(out) < klen; ct++ {
+		d.Reset()
+		d.Write(z)
+		d.Write([]byte{byte(ct >> 24), byte(ct >> 16), byte(ct >> 8), byte(ct)})
+		out = d.Sum(out)
+	}
+	return out[:klen]
+}
+
+func (d *sm3Digest) Reset() {
+	d.v = sm3IV
+	d.bufLen = 0
+	d.total = 0
+}
+
+func (d *sm3Digest) Size() int      { return 32 }
+func (d *sm3Digest) BlockSize() int { return 64 }
+
+func (d *sm3Digest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.total += uint64(n)
+	for len(p) > 0 {
+		toCopy := minInt(64-d.bufLen, len(p))
+		copy(d.block[d.bufLen:], p[:toCopy])
+		d.bufLen += toCopy
+		p = p[toCopy:]
+		if d.bufLen == 64 {
+			d.compress(&d.v, d.block[:])
+			d.bufLen = 0
+		}
+	}
+	return n, nil
+}
+
+// finish 在副本上填充并返回最终状态，d 本身可继续写入。
+func (d *sm3Digest) finish() [8]uint32 {
+	v := d.v
+	block := d.block
+	sm3PadAndProcess(d.compress, &v, block[:], d.bufLen, int64(d.total*8))
+	return v
+}
+
+func (d *sm3Digest) Sum(in []byte) []byte {
+	out := sm3Bytes(d.finish())
+	return append(in, out[:]...)
+}
+
+func sm3PadAndProcess(compress sm3BlockFunc, v *[8]uint32, block []byte, bufLen int, bitLen int64) {
+	block[bufLen] = 0x80
+	bufLen++
+	if bufLen > 56 {
+		for i := bufLen; i < 64; i++ {
+			block[i] = 0
+		}
+		compress(v, block)
+		bufLen = 0
+	}
+	for i := bufLen; i < 56; i++ {
+		block[i] = 0
+	}
+	b := uint64(bitLen)
+	block[56] = byte(b >> 56)
+	block[57] = byte(b >> 48)
+	block[58] = byte(b >> 40)
+	block[59] = byte(b >> 32)
+	block[60] = byte(b >> 24)
+	block[61] = byte(b >> 16)
+	block[62] = byte(b >> 8)
+	block[63] = byte(b)
+	compress(v, block)
+}
+
+func sm3Compress(v *[8]uint32, block []byte) {
+	var w [68]uint32
+	var w1 [64]uint32
+	for i := 0; i < 16; i++ {
+		idx := i * 4
+		w[i] = uint32(block[idx])<<24 | uint32(block[idx+1])<<16 | uint32(block[idx+2])<<8 | uint32(block[idx+3])
+	}
+	for j := 16; j < 68; j++ {
+		x := w[j-16] ^ w[j-9] ^ rotl(w[j-3], 15)
+		w[j] = p1(x) ^ rotl(w[j-13], 7) ^ w[j-6]
+	}
+	for j := 0; j < 64; j++ {
+		w1[j] = w[j] ^ w[j+4]
+	}
+	a, b, c, d := v[0], v[1], v[2], v[3]
+	e, f, g, h := v[4], v[5], v[6], v[7]
+	for j := 0; j < 64; j++ {
+		ss1 := rotl((rotl(a, 12)+e+rotl(t(j), j))&0xFFFFFFFF, 7)
+		ss2 := ss1 ^ rotl(a, 12)
+		tt1 := (ff(j, a, b, c) + d + ss2 + w1[j]) & 0xFFFFFFFF
+		tt2 := (gg(j, e, f, g) + h + ss1 + w[j]) & 0xFFFFFFFF
+		d = c
+		c = rotl(b, 9)
+		b = a
+		a = tt1
+		h = g
+		g = rotl(f, 19)
+		f = e
+		e = p0(tt2)
+	}
+	v[0] ^= a
+	v[1] ^= b
+	v[2] ^= c
+	v[3] ^= d
+	v[4] ^= e
+	v[5] ^= f
+	v[6] ^= g
+	v[7] ^= h
+}
+
+func ff(j int, x, y, z uint32) uint32 {
+	if j < 16 {
+		return x ^ y ^ z
+	}
+	return (x & y) | (x & z) | (y & z)
+}
+func gg(j int, x, y, z uint32) uint32 {
+	if j < 16 {
+		return x ^ y ^ z
+	}
+	return (x & y) | (^x & z)
+}
+func p0(x uint32) uint32 { return x ^ rotl(x, 9) ^ rotl(x, 17) }
+func p1(x uint32) uint32 { return x ^ rotl(x, 15) ^ rotl(x, 23) }
+func t(j int) uint32 {
+	if j < 16 {
+		return 0x79CC4519
+	}
+	return 0x7A879D8A
+}
+func rotl(x uint32, n int) uint32 {
+	n &= 31
+	if n == 0 {
+		return x
+	}
+	return (x << n) | (x >> (32 - n))
+}
+
+func sm3Bytes(v [8]uint32) [32]byte {
+	var out [32]byte
+	for i := 0; i < 8; i++ {
+		idx := i * 4
+		out[idx] = byte(v[i] >> 24)
+		out[idx+1] = byte(v[i] >> 16)
+		out[idx+2] = byte(v[i] >> 8)
+		out[idx+3] = byte(v[i])
+	}
+	return out
+}
+
+func sm3ToHex(v [8]uint32) string {
+	out := sm3Bytes(v)
+	const hex = "0123456789abcdef"
+	dst := make([]byte, 64)
+	for i, b := range out {
+		dst[i*2] = hex[b>>4]
+		dst[i*2+1] = hex[b&0x0F]
+	}
+	return string(dst)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
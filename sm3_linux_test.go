@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 在读取过程中（进度回调里）改写文件，确认 computeSM3File 能发现并按 Retries 重试。
+
+func TestComputeSM3FileDetectsChanges(t *testing.T) {
+	original := make([]byte, 1<<20)
+	for i := range original {
+		original[i] = byte(i * 31)
+	}
+	tests := []struct {
+		name   string
+		mutate func(t *testing.T, path string)
+	}{
+		{"append", func(t *testing.T, path string) {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte("more"))
+			f.Close()
+		}},
+		{"truncate", func(t *testing.T, path string) {
+			if err := os.Truncate(path, 1000); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"rewrite same size", func(t *testing.T, path string) {
+			fi, err := os.Stat(path)
+			if err == nil {
+				err = os.WriteFile(path, make([]byte, fi.Size()), 0644)
+			}
+			if err == nil {
+				mtime := fi.ModTime().Add(time.Second)
+				err = os.Chtimes(path, mtime, mtime)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"replace", func(t *testing.T, path string) {
+			tmp := path + ".new"
+			if err := os.WriteFile(tmp, []byte("replacement"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "growing.log")
+
+			// 每次读取都被改写：用完重试次数后返回 errFileChanged。
+			if err := os.WriteFile(path, original, 0644); err != nil {
+				t.Fatal(err)
+			}
+			attempts := 0
+			_, err := computeSM3File(path, hashOptions{Retries: 2}, func(done, total int64) {
+				if done == 0 {
+					attempts++
+					tt.mutate(t, path)
+				}
+			})
+			if !errors.Is(err, errFileChanged) || classifyError(err) != errKindChanged {
+				t.Errorf("always changing: err = %v, want errFileChanged", err)
+			}
+			if attempts != 3 {
+				t.Errorf("always changing: %d attempts, want 3 (1 + 2 retries)", attempts)
+			}
+
+			// 只在第一次读取时被改写：重试得到改写后内容的摘要。
+			if err := os.WriteFile(path, original, 0644); err != nil {
+				t.Fatal(err)
+			}
+			attempts = 0
+			got, err := computeSM3File(path, hashOptions{Retries: 2}, func(done, total int64) {
+				if done == 0 {
+					attempts++
+					if attempts == 1 {
+						tt.mutate(t, path)
+					}
+				}
+			})
+			final, rerr := os.ReadFile(path)
+			if rerr != nil {
+				t.Fatal(rerr)
+			}
+			if err != nil || got != sm3OneShot(final) || attempts != 2 {
+				t.Errorf("changed once: got %s, %v after %d attempts; want %s after 2", got, err, attempts, sm3OneShot(final))
+			}
+		})
+	}
+}
+
+// TestComputeSM3FileNoRetries 确认 Retries 为 0 时第一次检测到变化就返回。
+func TestComputeSM3FileNoRetries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(path, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	attempts := 0
+	_, err := computeSM3File(path, hashOptions{}, func(done, total int64) {
+		if done == 0 {
+			attempts++
+			os.Truncate(path, 10)
+		}
+	})
+	if !errors.Is(err, errFileChanged) || attempts != 1 {
+		t.Errorf("err = %v after %d attempts, want errFileChanged after 1", err, attempts)
+	}
+}
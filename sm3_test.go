@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	sm3Large = flag.Bool("sm3.large", false, "run the 4 GiB generated-data SM3 vector")
+	sm3Seed  = flag.Int64("sm3.seed", 1, "seed for TestSM3Differential")
+)
+
+// sm3Reference 按 GM/T 0004 第 5.2 节先填充整条消息再逐块压缩，不经过流式缓冲，作为差分比较的基准。
+func sm3Reference(msg []byte) [32]byte {
+	padded := make([]byte, 0, len(msg)+72)
+	padded = append(padded, msg...)
+	padded = append(padded, 0x80)
+	for len(padded)%64 != 56 {
+		padded = append(padded, 0)
+	}
+	padded = binary.BigEndian.AppendUint64(padded, uint64(len(msg))*8)
+	v := sm3IV
+	for i := 0; i < len(padded); i += 64 {
+		sm3Compress(&v, padded[i:i+64])
+	}
+	return sm3Bytes(v)
+}
+
+// standardVectors 是标准文本中给出的示例。ISO/IEC 10118-3:2018 第 14 号专用散列函数即 SM3，
+// 其示例消息与 GM/T 0004-2012 附录 A 相同，这里按来源分别列出，两者任何一处改动都会被发现。
+var standardVectors = []struct {
+	source string
+	msg    []byte
+	want   string
+}{
+	{"GM/T 0004-2012 附录 A.1", []byte("abc"),
+		"66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{"GM/T 0004-2012 附录 A.2", bytes.Repeat([]byte("abcd"), 16),
+		"debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	{"ISO/IEC 10118-3:2018 dedicated hash-function 14, example 1", []byte("abc"),
+		"66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{"ISO/IEC 10118-3:2018 dedicated hash-function 14, example 2", bytes.Repeat([]byte("abcd"), 16),
+		"debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+}
+
+func TestSM3StandardVectors(t *testing.T) {
+	for _, v := range standardVectors {
+		if got := sm3OneShot(v.msg); got != v.want {
+			t.Errorf("%s: got %s, want %s", v.source, got, v.want)
+		}
+		if ref := sm3Reference(v.msg); hex.EncodeToString(ref[:]) != v.want {
+			t.Errorf("%s: sm3Reference = %x, want %s", v.source, ref, v.want)
+		}
+	}
+}
+
+func TestSM3LengthVectors(t *testing.T) {
+	for _, v := range lengthVectors {
+		msg := bytes.Repeat([]byte("a"), v.n)
+		for _, chunk := range []int{v.n, 1, 63, 64, 65, 4096} {
+			if chunk <= 0 || chunk > v.n || v.n > 1000 && chunk < 64 {
+				continue
+			}
+			d := newSM3Digest()
+			for rest := msg; len(rest) > 0; rest = rest[min(chunk, len(rest)):] {
+				d.Write(rest[:min(chunk, len(rest))])
+			}
+			if got := hex.EncodeToString(d.Sum(nil)); got != v.want {
+				t.Errorf("'a'*%d in %d-byte writes = %s, want %s", v.n, chunk, got, v.want)
+			}
+		}
+		if v.n <= 1000 {
+			if ref := sm3Reference(msg); hex.EncodeToString(ref[:]) != v.want {
+				t.Errorf("sm3Reference('a'*%d) = %x, want %s", v.n, ref, v.want)
+			}
+		}
+	}
+}
+
+// TestSM3PaddingBoundaries 对 0..3 个分组长度内的每个长度比较流式实现与 sm3Reference。
+func TestSM3PaddingBoundaries(t *testing.T) {
+	msg := make([]byte, 192)
+	for i := range msg {
+		msg[i] = byte(i*7 + 3)
+	}
+	for n := 0; n <= len(msg); n++ {
+		want := sm3Reference(msg[:n])
+		if got := sm3OneShot(msg[:n]); got != hex.EncodeToString(want[:]) {
+			t.Errorf("%d bytes: got %s, want %x", n, got, want)
+		}
+	}
+}
+
+// TestSM3Differential 生成随机消息（长度偏向分组边界附近），分别整段写入、随机分段写入并在中途取摘要，
+// 结果都应与 sm3Reference 一致。失败时用 -sm3.seed 复现。
+func TestSM3Differential(t *testing.T) {
+	rounds := 2000
+	if testing.Short() {
+		rounds = 200
+	}
+	rng := rand.New(rand.NewSource(*sm3Seed))
+	for i := 0; i < rounds; i++ {
+		var n int
+		if rng.Intn(2) == 0 {
+			n = 64*rng.Intn(8) + 55 + rng.Intn(10) // 55..64 附近
+		} else {
+			n = rng.Intn(4096)
+		}
+		msg := make([]byte, n)
+		rng.Read(msg)
+		want := sm3Reference(msg)
+		if got := sm3OneShot(msg); got != hex.EncodeToString(want[:]) {
+			t.Fatalf("seed %d round %d len %d: one-shot = %s, want %x", *sm3Seed, i, n, got, want)
+		}
+		d := newSM3Digest()
+		mid := rng.Intn(n + 1)
+		for rest := msg; len(rest) > 0; {
+			k := min(len(rest), rng.Intn(130)+1)
+			if done := n - len(rest); done <= mid && mid < done+k {
+				d.Write(rest[:mid-done])
+				if got, partial := d.Sum(nil), sm3Reference(msg[:mid]); !bytes.Equal(got, partial[:]) {
+					t.Fatalf("seed %d round %d len %d: sum at %d = %x, want %x", *sm3Seed, i, n, mid, got, partial)
+				}
+				d.Write(rest[mid-done : k])
+			} else {
+				d.Write(rest[:k])
+			}
+			rest = rest[k:]
+		}
+		if got := d.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Fatalf("seed %d round %d len %d: chunked = %x, want %x", *sm3Seed, i, n, got, want)
+		}
+	}
+}
+
+// TestSM3Large 计算 4 GiB 生成数据，约需一分钟，只在 go test -sm3.large 时运行。
+func TestSM3Large(t *testing.T) {
+	if !*sm3Large || testing.Short() {
+		t.Skip("pass -sm3.large to run the 4 GiB vector")
+	}
+	if r := checkLargeVector(); !r.OK {
+		t.Errorf("got %s, want %s", r.Got, r.Want)
+	}
+}
+
+// FuzzSM3 比较流式实现（按 split 分两段写入，中途取摘要）、经临时文件用每种读取策略调用的
+// computeSM3File 与一次性填充的 sm3Reference。
+func FuzzSM3(f *testing.F) {
+	for _, n := range []int{0, 1, 3, 55, 56, 63, 64, 65, 119, 120, 128, 1000, 4096, 5000} {
+		f.Add(bytes.Repeat([]byte("abc"), n)[:n], uint16(n/2))
+	}
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, msg []byte, split uint16) {
+		want := sm3Reference(msg)
+		if got := sm3OneShot(msg); got != hex.EncodeToString(want[:]) {
+			t.Fatalf("one-shot(%d bytes) = %s, want %x", len(msg), got, want)
+		}
+		k := int(split) % (len(msg) + 1)
+		d := newSM3Digest()
+		d.Write(msg[:k])
+		if got, want := d.Sum(nil), sm3Reference(msg[:k]); !bytes.Equal(got, want[:]) {
+			t.Fatalf("sum after %d bytes = %x, want %x", k, got, want)
+		}
+		d.Write(msg[k:])
+		if got := d.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Fatalf("split at %d of %d = %x, want %x", k, len(msg), got, want)
+		}
+		d.Reset()
+		d.Write(msg)
+		if got := d.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Fatalf("after Reset = %x, want %x", got, want)
+		}
+
+		// 最小缓冲区 4 KiB，较长的输入会跨越多次读取。
+		path := filepath.Join(dir, "input")
+		if err := os.WriteFile(path, msg, 0600); err != nil {
+			t.Fatal(err)
+		}
+		for s := range ioStrategyNames {
+			opt := hashOptions{IO: ioOptions{Strategy: ioStrategy(s), BufferKB: minIOBufferKB}}
+			got, err := computeSM3File(path, opt, nil)
+			if err != nil || got != hex.EncodeToString(want[:]) {
+				t.Fatalf("computeSM3File(%d bytes, %s) = %s, %v; want %x", len(msg), ioStrategy(s), got, err, want)
+			}
+		}
+	})
+}
@@ -1,15 +1,18 @@
+//go:build windows
+
 package main
 
 import (
 	"fmt"
-	"io"
-	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf16"
 	"unsafe"
 )
 
@@ -22,6 +25,7 @@ var (
 	comctl32 = syscall.NewLazyDLL("comctl32.dll")
 	comdlg32 = syscall.NewLazyDLL("comdlg32.dll")
 	shell32  = syscall.NewLazyDLL("shell32.dll")
+	ole32    = syscall.NewLazyDLL("ole32.dll")
 
 	procGetModuleHandleW     = kernel32.NewProc("GetModuleHandleW")
 	procPostQuitMessage      = user32.NewProc("PostQuitMessage")
@@ -48,14 +52,24 @@ var (
 	procCloseClipboard       = user32.NewProc("CloseClipboard")
 	procEmptyClipboard       = user32.NewProc("EmptyClipboard")
 	procSetClipboardData     = user32.NewProc("SetClipboardData")
+	procGetClipboardData     = user32.NewProc("GetClipboardData")
+	procIsClipboardFormatAvl = user32.NewProc("IsClipboardFormatAvailable")
 	procLoadImageW           = user32.NewProc("LoadImageW")
 	procGlobalAlloc          = kernel32.NewProc("GlobalAlloc")
 	procGlobalLock           = kernel32.NewProc("GlobalLock")
 	procGlobalUnlock         = kernel32.NewProc("GlobalUnlock")
 	procEnableWindow         = user32.NewProc("EnableWindow")
+	procGetWindowTextW       = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW = user32.NewProc("GetWindowTextLengthW")
+	procInvalidateRect       = user32.NewProc("InvalidateRect")
 	procDragAcceptFiles      = shell32.NewProc("DragAcceptFiles")
 	procDragQueryFileW       = shell32.NewProc("DragQueryFileW")
 	procDragFinish           = shell32.NewProc("DragFinish")
+	procSHBrowseForFolderW   = shell32.NewProc("SHBrowseForFolderW")
+	procSHGetPathFromIDListW = shell32.NewProc("SHGetPathFromIDListW")
+	procCoTaskMemFree        = ole32.NewProc("CoTaskMemFree")
+	procGetWindowPlacement   = user32.NewProc("GetWindowPlacement")
+	procMonitorFromRect      = user32.NewProc("MonitorFromRect")
 )
 
 const (
@@ -72,6 +86,14 @@ const (
 	ES_MULTILINE   = 0x0004
 	ES_AUTOVSCROLL = 0x0040
 	ES_READONLY    = 0x0800
+	ES_AUTOHSCROLL = 0x0080
+
+	CBS_DROPDOWNLIST = 0x0003
+	CB_ADDSTRING     = 0x0143
+	CB_GETCURSEL     = 0x0147
+	CB_SETCURSEL     = 0x014E
+	CB_RESETCONTENT  = 0x014B
+	CBN_SELCHANGE    = 1
 
 	BS_GROUPBOX     = 0x00000007
 	BS_AUTOCHECKBOX = 0x00000003
@@ -86,26 +108,59 @@ const (
 	BST_UNCHECKED = 0
 	BST_CHECKED   = 1
 
+	LVS_REPORT           = 0x0001
+	LVS_SHOWSELALWAYS    = 0x0008
+	LVS_OWNERDATA        = 0x1000
+	LVS_EX_GRIDLINES     = 0x00000001
+	LVS_EX_FULLROWSELECT = 0x00000020
+
+	LVM_SETITEMSTATE             = 0x102B
+	LVM_GETNEXTITEM              = 0x100C
+	LVM_SETITEMCOUNT             = 0x102F
+	LVM_SETEXTENDEDLISTVIEWSTYLE = 0x1036
+	LVM_INSERTCOLUMNW            = 0x1061
+	LVM_SETCOLUMNW               = 0x1060
+	LVM_ENSUREVISIBLE            = 0x1013
+
+	LVCF_WIDTH    = 0x0002
+	LVCF_TEXT     = 0x0004
+	LVIF_TEXT     = 0x0001
+	LVIS_FOCUSED  = 0x0001
+	LVIS_SELECTED = 0x0002
+	LVNI_SELECTED = 0x0002
+
+	LVSICF_NOINVALIDATEALL = 0x0001
+	LVSICF_NOSCROLL        = 0x0002
+
+	LVN_GETDISPINFOW = ^uint32(176) // LVN_FIRST(-100) - 77
+
 	WM_DESTROY   = 0x0002
 	WM_COMMAND   = 0x0111
 	WM_CREATE    = 0x0001
 	WM_APP       = 0x8000
 	WM_DROPFILES = 0x0233
+	WM_ACTIVATE  = 0x0006
 	WM_SETFONT   = 0x0030
 	WM_SIZE      = 0x0005
+	WM_NOTIFY    = 0x004E
 
 	MSG_PROGRESS = WM_APP + 1
 	MSG_DONE     = WM_APP + 2
-	MSG_ERROR    = WM_APP + 3
 	MSG_REFRESH  = WM_APP + 4
+	MSG_QUEUE    = WM_APP + 5
+	MSG_WATCH    = WM_APP + 6
 
-	IDC_ARROW = 32512
-	SW_SHOW   = 5
+	IDC_ARROW          = 32512
+	SW_SHOW            = 5
+	SW_SHOWMAXIMIZED   = 3
+	BFFM_INITIALIZED   = 1
+	BFFM_SETSELECTIONW = 0x0400 + 103
 
 	CF_UNICODETEXT = 13
 	GMEM_MOVEABLE  = 0x0002
 
-	ICC_PROGRESS_CLASS = 0x00000020
+	ICC_LISTVIEW_CLASSES = 0x00000001
+	ICC_PROGRESS_CLASS   = 0x00000020
 
 	IMAGE_ICON     = 1
 	LR_DEFAULTSIZE = 0x00000040
@@ -119,7 +174,12 @@ const (
 
 	margin         int32 = 6
 	groupHeight    int32 = 50
+	queueHeight    int32 = 140
+	queueBtnHeight int32 = 24
+	textRowHeight  int32 = 24
 	progressHeight int32 = 22
+	statsHeight    int32 = 18
+	rowGap         int32 = 4
 	btnWidth       int32 = 78
 	btnHeight      int32 = 26
 )
@@ -137,6 +197,23 @@ const (
 	idBtnExit   = 1010
 	idProgBar   = 1011
 	idProgLabel = 1012
+	idQueueList = 1013
+	idBtnRemove = 1014
+	idBtnUp     = 1015
+	idBtnDown   = 1016
+	idBtnFirst  = 1017
+	idBtnRetry  = 1018
+	idChkLock   = 1019
+	idTotalBar  = 1020
+	idChkCache  = 1021
+	idEditText  = 1022
+	idComboEnc  = 1023
+	idBtnText   = 1024
+	idComboFmt  = 1025
+	idEditExp   = 1026
+	idBtnPaste  = 1027
+	idBtnWatch  = 1028
+	idComboLang = 1029
 )
 
 type hwnd = syscall.Handle
@@ -167,11 +244,74 @@ type msg struct {
 
 type rect struct{ left, top, right, bottom int32 }
 
+type windowPlacement struct {
+	length           uint32
+	flags            uint32
+	showCmd          uint32
+	ptMinPosition    struct{ x, y int32 }
+	ptMaxPosition    struct{ x, y int32 }
+	rcNormalPosition rect
+}
+
+type nmhdr struct {
+	hwndFrom syscall.Handle
+	idFrom   uintptr
+	code     uint32
+}
+
+type lvItem struct {
+	mask       uint32
+	iItem      int32
+	iSubItem   int32
+	state      uint32
+	stateMask  uint32
+	pszText    *uint16
+	cchTextMax int32
+	iImage     int32
+	lParam     uintptr
+	iIndent    int32
+	iGroupId   int32
+	cColumns   uint32
+	puColumns  uintptr
+	piColFmt   uintptr
+	iGroup     int32
+}
+
+type nmlvDispInfo struct {
+	hdr  nmhdr
+	item lvItem
+}
+
+type lvColumn struct {
+	mask       uint32
+	fmt        int32
+	cx         int32
+	pszText    *uint16
+	cchTextMax int32
+	iSubItem   int32
+	iImage     int32
+	iOrder     int32
+	cxMin      int32
+	cxDefault  int32
+	cxIdeal    int32
+}
+
 type initCommonControlsEx struct {
 	dwSize uint32
 	dwICC  uint32
 }
 
+type browseInfoW struct {
+	hwndOwner      syscall.Handle
+	pidlRoot       uintptr
+	pszDisplayName *uint16
+	lpszTitle      *uint16
+	ulFlags        uint32
+	lpfn           uintptr
+	lParam         uintptr
+	iImage         int32
+}
+
 type openFileNameW struct {
 	lStructSize       uint32
 	hwndOwner         syscall.Handle
@@ -207,30 +347,75 @@ var (
 	progressTextHWND hwnd
 	progressHWND     hwnd
 	progressLblHWND  hwnd
+	totalTextHWND    hwnd
+	totalHWND        hwnd
+	totalLblHWND     hwnd
+	statsHWND        hwnd
 	chkSizeHWND      hwnd
 	chkTimeHWND      hwnd
 	chkUpperHWND     hwnd
+	chkLockHWND      hwnd
+	chkCacheHWND     hwnd
 	btnBrowseHWND    hwnd
 	btnClearHWND     hwnd
 	btnCopyHWND      hwnd
 	btnSaveHWND      hwnd
 	btnStartHWND     hwnd
 	btnExitHWND      hwnd
+	queueHWND        hwnd
+	btnRemoveHWND    hwnd
+	btnUpHWND        hwnd
+	btnDownHWND      hwnd
+	btnFirstHWND     hwnd
+	btnRetryHWND     hwnd
+	textLabelHWND    hwnd
+	textEditHWND     hwnd
+	textEncHWND      hwnd
+	btnTextHWND      hwnd
+	fmtLabelHWND     hwnd
+	fmtComboHWND     hwnd
+	langLabelHWND    hwnd
+	langComboHWND    hwnd
+	expLabelHWND     hwnd
+	expEditHWND      hwnd
+	btnPasteHWND     hwnd
+	btnWatchHWND     hwnd
 	uiFont           syscall.Handle
 	monoFont         syscall.Handle
 
 	outputMu   sync.Mutex
 	outputText string
-	errMu      sync.Mutex
-	errText    string
 
 	queueMu       sync.Mutex
-	queue         []string
 	workerRunning bool
+	taskQueue     = newJobQueue(requestQueueRefresh)
+	queueView     []queueEntry
+	queueDirty    atomic.Bool
+	runSkipped    atomic.Int64
+
+	expectedDigest string // 开始批量计算时读取的期望值
+	lastClipDigest string // 最近一次从剪贴板自动填入的内容，避免反复填入
+	batch          batchProgress
+	guiCache       *hashCache
 )
 
 func main() {
 	os.Setenv("GOTELEMETRY", "off")
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+	currentLang = detectLanguage()
+	if err := checkSelfTest(); err != nil {
+		showError(err.Error())
+		os.Exit(exitSelfTest)
+	}
+	if err := openRunLog(runLogConfigFromEnv()); err != nil {
+		showError(tr("msg.runLogUnavailable", err))
+	}
+	defer closeRunLog()
+	loadGUISettings()
+	currentLang, _ = parseLanguage(guiSettings.Language)
+	win := guiSettings.Window
 	initCommonControls()
 	hInstance := getModuleHandle()
 	iconBig := loadAppIcon(0)
@@ -249,20 +434,24 @@ func main() {
 	if atom, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wcx))); atom == 0 {
 		panic(err)
 	}
-	title := toUTF16Ptr("SM3校验工具 v1.0 (Go)")
+	title := toUTF16Ptr(tr("app.title"))
 	hw, _, err := procCreateWindowExW.Call(
 		WS_EX_ACCEPTFILES,
 		uintptr(unsafe.Pointer(className)),
 		uintptr(unsafe.Pointer(title)),
 		WS_OVERLAPPEDWINDOW|WS_VISIBLE,
-		120, 120, 620, 470,
+		uintptr(win.X), uintptr(win.Y), uintptr(win.Width), uintptr(win.Height),
 		0, 0, uintptr(hInstance), 0,
 	)
 	if hw == 0 {
 		panic(err)
 	}
 	mainHWND = hwnd(hw)
-	procShowWindow.Call(hw, SW_SHOW)
+	if win.Maximized {
+		procShowWindow.Call(hw, SW_SHOWMAXIMIZED)
+	} else {
+		procShowWindow.Call(hw, SW_SHOW)
+	}
 	procUpdateWindow.Call(hw)
 
 	var m msg
@@ -287,20 +476,32 @@ func wndProc(h hwnd, message uint32, wParam, lParam uintptr) uintptr {
 		handleCommand(wParam)
 	case MSG_PROGRESS:
 		setProgress(int(wParam))
+		refreshTotal()
 	case MSG_DONE:
 		setProgress(100)
+		refreshTotal()
 		updateButtons(true)
 		refreshOutput()
-	case MSG_ERROR:
-		updateButtons(true)
-		showError(currentError())
 	case MSG_REFRESH:
 		refreshOutput()
+	case MSG_QUEUE:
+		refreshQueue()
+	case MSG_WATCH:
+		drainWatchReady()
+	case WM_NOTIFY:
+		onNotify(lParam)
 	case WM_DROPFILES:
 		handleDrop(wParam)
 	case WM_SIZE:
 		layoutControls()
+	case WM_ACTIVATE:
+		if wParam&0xFFFF != 0 {
+			detectClipboardDigest()
+		}
+		ret, _, _ := procDefWindowProcW.Call(uintptr(h), uintptr(message), wParam, lParam)
+		return ret
 	case WM_DESTROY:
+		persistSettings()
 		procPostQuitMessage.Call(0)
 	default:
 		ret, _, _ := procDefWindowProcW.Call(uintptr(h), uintptr(message), wParam, lParam)
@@ -318,45 +519,197 @@ func createControls(h hwnd) {
 	outputHWND = createWindow("EDIT", "", WS_CHILD|WS_VISIBLE|WS_BORDER|WS_VSCROLL|ES_MULTILINE|ES_AUTOVSCROLL|ES_READONLY, WS_EX_CLIENTEDGE, 10, 10, 500, 180, h, idEditOut)
 	setFont(outputHWND, mono)
 
-	settingsHWND = createWindow("BUTTON", "显示选项", WS_CHILD|WS_VISIBLE|BS_GROUPBOX, 0, 10, 195, 500, groupHeight, h, 0)
-	chkSizeHWND = createWindow("BUTTON", "文件大小", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 20, 213, 82, 18, h, idChkSize)
-	chkTimeHWND = createWindow("BUTTON", "计算时间", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 112, 213, 82, 18, h, idChkTime)
-	chkUpperHWND = createWindow("BUTTON", "结果大写", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 204, 213, 82, 18, h, idChkUpper)
-	sendMessage(chkSizeHWND, BM_SETCHECK, BST_CHECKED, 0)
-	sendMessage(chkTimeHWND, BM_SETCHECK, BST_CHECKED, 0)
-	sendMessage(chkUpperHWND, BM_SETCHECK, BST_CHECKED, 0)
+	settingsHWND = createWindow("BUTTON", "", WS_CHILD|WS_VISIBLE|BS_GROUPBOX, 0, 10, 195, 500, groupHeight, h, 0)
+	chkSizeHWND = createWindow("BUTTON", "", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 20, 213, 82, 18, h, idChkSize)
+	chkTimeHWND = createWindow("BUTTON", "", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 112, 213, 82, 18, h, idChkTime)
+	chkUpperHWND = createWindow("BUTTON", "", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 204, 213, 82, 18, h, idChkUpper)
+	chkLockHWND = createWindow("BUTTON", "", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 296, 213, 82, 18, h, idChkLock)
+	chkCacheHWND = createWindow("BUTTON", "", WS_CHILD|WS_VISIBLE|BS_AUTOCHECKBOX, 0, 388, 213, 82, 18, h, idChkCache)
+	setChecked(chkSizeHWND, guiSettings.ShowSize)
+	setChecked(chkTimeHWND, guiSettings.ShowTime)
+	setChecked(chkUpperHWND, guiSettings.Upper)
+	setChecked(chkLockHWND, guiSettings.DenyWrite)
+	setChecked(chkCacheHWND, guiSettings.UseCache)
 	setFont(chkSizeHWND, font)
 	setFont(chkTimeHWND, font)
 	setFont(chkUpperHWND, font)
+	setFont(chkLockHWND, font)
+	setFont(chkCacheHWND, font)
 
-	algoHWND = createWindow("BUTTON", "哈希算法", WS_CHILD|WS_VISIBLE|BS_GROUPBOX, 0, 10, 245, 500, groupHeight, h, 0)
-	sm3LabelHWND = createWindow("STATIC", "SM3 (国密)", WS_CHILD|WS_VISIBLE, 0, 20, 265, 120, 18, h, 0)
+	algoHWND = createWindow("BUTTON", "", WS_CHILD|WS_VISIBLE|BS_GROUPBOX, 0, 10, 245, 500, groupHeight, h, 0)
+	sm3LabelHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 20, 265, 120, 18, h, 0)
 	setFont(sm3LabelHWND, font)
-
-	progressTextHWND = createWindow("STATIC", "进度", WS_CHILD|WS_VISIBLE, 0, 10, 295, 40, 18, h, idProgLabel)
+	fmtLabelHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 150, 265, 60, 18, h, 0)
+	setFont(fmtLabelHWND, font)
+	fmtComboHWND = createWindow("COMBOBOX", "", WS_CHILD|WS_VISIBLE|WS_VSCROLL|WS_TABSTOP|CBS_DROPDOWNLIST, 0, 214, 262, 110, 200, h, idComboFmt)
+	setFont(fmtComboHWND, font)
+	fmtSel, _ := parseDigestFormat(guiSettings.Format)
+	fillCombo(fmtComboHWND, digestFormatLabels(), int(fmtSel))
+	langLabelHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 340, 265, 60, 18, h, 0)
+	setFont(langLabelHWND, font)
+	langComboHWND = createWindow("COMBOBOX", "", WS_CHILD|WS_VISIBLE|WS_VSCROLL|WS_TABSTOP|CBS_DROPDOWNLIST, 0, 404, 262, 90, 200, h, idComboLang)
+	setFont(langComboHWND, font)
+	fillCombo(langComboHWND, languageChoiceLabels(), languageChoiceIndex(guiSettings.Language))
+
+	progressTextHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 10, 295, 40, 18, h, idProgLabel)
 	setFont(progressTextHWND, font)
 	progressHWND = createWindow("msctls_progress32", "", WS_CHILD|WS_VISIBLE, WS_EX_CLIENTEDGE, 64, 295, 320, 18, h, idProgBar)
 	sendMessage(progressHWND, PBM_SETRANGE, 0, uintptr((100<<16)|0))
 	progressLblHWND = createWindow("STATIC", "0%", WS_CHILD|WS_VISIBLE, 0, 390, 295, 40, 18, h, 0)
 	setFont(progressLblHWND, font)
+	totalTextHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 10, 320, 40, 18, h, 0)
+	setFont(totalTextHWND, font)
+	totalHWND = createWindow("msctls_progress32", "", WS_CHILD|WS_VISIBLE, WS_EX_CLIENTEDGE, 64, 320, 320, 18, h, idTotalBar)
+	sendMessage(totalHWND, PBM_SETRANGE, 0, uintptr((100<<16)|0))
+	totalLblHWND = createWindow("STATIC", "0%", WS_CHILD|WS_VISIBLE, 0, 390, 320, 40, 18, h, 0)
+	setFont(totalLblHWND, font)
+	statsHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 10, 345, 400, 18, h, 0)
+	setFont(statsHWND, font)
+
+	btnBrowseHWND = createButton("", 10, 330, h, idBtnBrowse, font)
+	btnClearHWND = createButton("", 90, 330, h, idBtnClear, font)
+	btnCopyHWND = createButton("", 170, 330, h, idBtnCopy, font)
+	btnSaveHWND = createButton("", 250, 330, h, idBtnSave, font)
+	btnWatchHWND = createButton("", 330, 330, h, idBtnWatch, font)
+	btnStartHWND = createButton("", 330, 330, h, idBtnStart, font)
+	btnExitHWND = createButton("", 410, 330, h, idBtnExit, font)
+
+	queueHWND = createWindow("SysListView32", "", WS_CHILD|WS_VISIBLE|WS_BORDER|WS_TABSTOP|LVS_REPORT|LVS_SHOWSELALWAYS|LVS_OWNERDATA, WS_EX_CLIENTEDGE, 10, 10, 400, queueHeight, h, idQueueList)
+	setFont(queueHWND, font)
+	sendMessage(queueHWND, LVM_SETEXTENDEDLISTVIEWSTYLE, 0, LVS_EX_FULLROWSELECT|LVS_EX_GRIDLINES)
+	insertColumn(queueHWND, 0, "", 48)
+	insertColumn(queueHWND, 1, "", 64)
+	insertColumn(queueHWND, 2, "", 360)
+	btnUpHWND = createButton("", 0, 0, h, idBtnUp, font)
+	btnDownHWND = createButton("", 0, 0, h, idBtnDown, font)
+	btnFirstHWND = createButton("", 0, 0, h, idBtnFirst, font)
+	btnRemoveHWND = createButton("", 0, 0, h, idBtnRemove, font)
+	btnRetryHWND = createButton("", 0, 0, h, idBtnRetry, font)
+
+	textLabelHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 0, 0, 40, 18, h, 0)
+	setFont(textLabelHWND, font)
+	textEditHWND = createWindow("EDIT", "", WS_CHILD|WS_VISIBLE|WS_BORDER|WS_TABSTOP|ES_AUTOHSCROLL, WS_EX_CLIENTEDGE, 0, 0, 300, textRowHeight, h, idEditText)
+	setFont(textEditHWND, mono)
+	textEncHWND = createWindow("COMBOBOX", "", WS_CHILD|WS_VISIBLE|WS_VSCROLL|WS_TABSTOP|CBS_DROPDOWNLIST, 0, 0, 0, 100, 200, h, idComboEnc)
+	setFont(textEncHWND, font)
+	encSel := 0
+	for i, name := range textInputChoices() {
+		if name == guiSettings.TextEncoding {
+			encSel = i
+		}
+	}
+	fillCombo(textEncHWND, textInputLabels(), encSel)
+	btnTextHWND = createButton("", 0, 0, h, idBtnText, font)
 
-	btnBrowseHWND = createButton("浏览...", 10, 330, h, idBtnBrowse, font)
-	btnClearHWND = createButton("清空", 90, 330, h, idBtnClear, font)
-	btnCopyHWND = createButton("复制", 170, 330, h, idBtnCopy, font)
-	btnSaveHWND = createButton("保存", 250, 330, h, idBtnSave, font)
-	btnStartHWND = createButton("开始", 330, 330, h, idBtnStart, font)
-	btnExitHWND = createButton("退出", 410, 330, h, idBtnExit, font)
+	expLabelHWND = createWindow("STATIC", "", WS_CHILD|WS_VISIBLE, 0, 0, 0, 40, 18, h, 0)
+	setFont(expLabelHWND, font)
+	expEditHWND = createWindow("EDIT", "", WS_CHILD|WS_VISIBLE|WS_BORDER|WS_TABSTOP|ES_AUTOHSCROLL, WS_EX_CLIENTEDGE, 0, 0, 300, textRowHeight, h, idEditExp)
+	setFont(expEditHWND, mono)
+	btnPasteHWND = createButton("", 0, 0, h, idBtnPaste, font)
 
 	procDragAcceptFiles.Call(uintptr(h), 1)
+	applyLanguage()
 	layoutControls()
 }
 
+// applyLanguage 按当前语言设置全部控件文字，切换语言时无需重启。
+func applyLanguage() {
+	labels := []struct {
+		h   hwnd
+		key string
+	}{
+		{settingsHWND, "grp.display"}, {chkSizeHWND, "chk.size"}, {chkTimeHWND, "chk.time"},
+		{chkUpperHWND, "chk.upper"}, {chkLockHWND, "chk.lock"}, {chkCacheHWND, "chk.cache"},
+		{algoHWND, "grp.algo"}, {sm3LabelHWND, "lbl.sm3"}, {fmtLabelHWND, "lbl.format"}, {langLabelHWND, "lbl.lang"},
+		{progressTextHWND, "lbl.progress"}, {totalTextHWND, "lbl.total"},
+		{btnBrowseHWND, "btn.browse"}, {btnClearHWND, "btn.clear"}, {btnCopyHWND, "btn.copy"},
+		{btnSaveHWND, "btn.save"}, {btnStartHWND, "btn.start"}, {btnExitHWND, "btn.exit"},
+		{btnUpHWND, "btn.up"}, {btnDownHWND, "btn.down"}, {btnFirstHWND, "btn.first"},
+		{btnRemoveHWND, "btn.remove"}, {btnRetryHWND, "btn.retry"},
+		{textLabelHWND, "lbl.text"}, {btnTextHWND, "btn.hashText"},
+		{expLabelHWND, "lbl.expected"}, {btnPasteHWND, "btn.paste"},
+	}
+	for _, l := range labels {
+		setLabel(l.h, tr(l.key))
+	}
+	if watching() {
+		setLabel(btnWatchHWND, tr("btn.stopWatch"))
+	} else {
+		setLabel(btnWatchHWND, tr("btn.watch"))
+	}
+	setLabel(mainHWND, tr("app.title"))
+	setColumnText(queueHWND, 0, tr("col.id"))
+	setColumnText(queueHWND, 1, tr("col.status"))
+	setColumnText(queueHWND, 2, tr("col.file"))
+	fillCombo(fmtComboHWND, digestFormatLabels(), int(sendMessage(fmtComboHWND, CB_GETCURSEL, 0, 0)))
+	fillCombo(textEncHWND, textInputLabels(), int(sendMessage(textEncHWND, CB_GETCURSEL, 0, 0)))
+	fillCombo(langComboHWND, languageChoiceLabels(), int(sendMessage(langComboHWND, CB_GETCURSEL, 0, 0)))
+	procInvalidateRect.Call(uintptr(queueHWND), 0, 1)
+}
+
+// fillCombo 替换下拉框的全部选项并选中 sel（越界时选第一项）。
+func fillCombo(h hwnd, items []string, sel int) {
+	sendMessage(h, CB_RESETCONTENT, 0, 0)
+	for _, item := range items {
+		sendMessage(h, CB_ADDSTRING, 0, uintptr(unsafe.Pointer(toUTF16Ptr(item))))
+	}
+	if sel < 0 || sel >= len(items) {
+		sel = 0
+	}
+	sendMessage(h, CB_SETCURSEL, uintptr(sel), 0)
+}
+
+func digestFormatLabels() []string {
+	labels := make([]string, len(digestFormats))
+	for i, df := range digestFormats {
+		labels[i] = tr(df.label)
+	}
+	return labels
+}
+
+// languageChoiceLabels 是语言下拉框的选项：自动，然后是各语言的自称。
+func languageChoiceLabels() []string {
+	return []string{tr("lang.auto"), "简体中文", "English"}
+}
+
+func languageChoiceIndex(code string) int {
+	for i, name := range languageNames {
+		if code == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func onLanguageChange() {
+	sel := int(sendMessage(langComboHWND, CB_GETCURSEL, 0, 0))
+	code := ""
+	if sel > 0 && sel <= len(languageNames) {
+		code = languageNames[sel-1]
+	}
+	guiSettings.Language = code
+	currentLang, _ = parseLanguage(code)
+	applyLanguage()
+	layoutControls()
+	persistSettings()
+}
+
 func createButton(text string, x, y int32, parent hwnd, id int32, font syscall.Handle) hwnd {
 	h := createWindow("BUTTON", text, WS_CHILD|WS_VISIBLE|BS_PUSHBUTTON|WS_TABSTOP, 0, x, y, btnWidth, btnHeight, parent, id)
 	setFont(h, font)
 	return h
 }
 
+func insertColumn(list hwnd, index int, text string, width int32) {
+	col := lvColumn{mask: LVCF_TEXT | LVCF_WIDTH, cx: width, pszText: toUTF16Ptr(text)}
+	sendMessage(list, LVM_INSERTCOLUMNW, uintptr(index), uintptr(unsafe.Pointer(&col)))
+}
+
+func setColumnText(list hwnd, index int, text string) {
+	col := lvColumn{mask: LVCF_TEXT, pszText: toUTF16Ptr(text)}
+	sendMessage(list, LVM_SETCOLUMNW, uintptr(index), uintptr(unsafe.Pointer(&col)))
+}
+
 func createWindow(class, title string, style uint32, exStyle int32, x, y, w, h int32, parent hwnd, id int32) hwnd {
 	ret, _, _ := procCreateWindowExW.Call(
 		uintptr(exStyle),
@@ -381,28 +734,43 @@ func layoutControls() {
 		return
 	}
 	btnY := h - margin - btnHeight
-	progressY := btnY - margin - progressHeight
+	statsY := btnY - margin - statsHeight
+	totalY := statsY - rowGap - progressHeight
+	progressY := totalY - rowGap - progressHeight
 	algoY := progressY - margin - groupHeight
 	settingsY := algoY - margin - groupHeight
+	expY := settingsY - margin - textRowHeight
+	textY := expY - rowGap - textRowHeight
+	queueY := textY - margin - queueHeight
 
-	outH := settingsY - margin
+	outH := queueY - margin
 	if outH < 80 {
 		shift := 80 - outH
+		queueY += shift
+		textY += shift
+		expY += shift
 		settingsY += shift
 		algoY += shift
 		progressY += shift
+		totalY += shift
+		statsY += shift
 		btnY += shift
 		outH = 80
 		if btnY+btnHeight+margin > h {
 			over := btnY + btnHeight + margin - h
+			queueY -= over
+			textY -= over
+			expY -= over
 			settingsY -= over
 			algoY -= over
 			progressY -= over
+			totalY -= over
+			statsY -= over
 			btnY -= over
-			if settingsY < margin {
-				settingsY = margin
+			if queueY < margin {
+				queueY = margin
 			}
-			outH = settingsY - margin
+			outH = queueY - margin
 			if outH < 40 {
 				outH = 40
 			}
@@ -412,13 +780,41 @@ func layoutControls() {
 	cw := maxInt32(w-2*margin, 120)
 	moveWindow(outputHWND, margin, margin, cw, outH)
 
+	listW := maxInt32(cw-btnWidth-margin, 80)
+	moveWindow(queueHWND, margin, queueY, listW, queueHeight)
+	qx := margin + listW + margin
+	qy := queueY
+	for _, b := range []hwnd{btnUpHWND, btnDownHWND, btnFirstHWND, btnRemoveHWND, btnRetryHWND} {
+		moveWindow(b, qx, qy, btnWidth, queueBtnHeight)
+		qy += queueBtnHeight + (queueHeight-5*queueBtnHeight)/4
+	}
+
+	encW := int32(100)
+	editX := margin + 42
+	editW := maxInt32(cw-42-encW-btnWidth-2*margin, 80)
+	moveWindow(textLabelHWND, margin, textY+3, 40, 18)
+	moveWindow(textEditHWND, editX, textY, editW, textRowHeight)
+	moveWindow(textEncHWND, editX+editW+margin, textY, encW, 200)
+	moveWindow(btnTextHWND, editX+editW+margin+encW+margin, textY, btnWidth, textRowHeight)
+
+	expW := maxInt32(cw-42-btnWidth-margin, 80)
+	moveWindow(expLabelHWND, margin, expY+3, 40, 18)
+	moveWindow(expEditHWND, editX, expY, expW, textRowHeight)
+	moveWindow(btnPasteHWND, editX+expW+margin, expY, btnWidth, textRowHeight)
+
 	moveWindow(settingsHWND, margin, settingsY, cw, groupHeight)
 	moveWindow(chkSizeHWND, margin+10, settingsY+18, 80, 20)
 	moveWindow(chkTimeHWND, margin+110, settingsY+18, 80, 20)
 	moveWindow(chkUpperHWND, margin+210, settingsY+18, 80, 20)
+	moveWindow(chkLockHWND, margin+310, settingsY+18, 80, 20)
+	moveWindow(chkCacheHWND, margin+410, settingsY+18, 80, 20)
 
 	moveWindow(algoHWND, margin, algoY, cw, groupHeight)
 	moveWindow(sm3LabelHWND, margin+10, algoY+18, 120, 20)
+	moveWindow(fmtLabelHWND, margin+150, algoY+20, 60, 20)
+	moveWindow(fmtComboHWND, margin+214, algoY+16, 110, 200)
+	moveWindow(langLabelHWND, margin+340, algoY+20, 60, 20)
+	moveWindow(langComboHWND, margin+404, algoY+16, 90, 200)
 
 	labelW := int32(42)
 	labelGap := int32(8)
@@ -431,14 +827,18 @@ func layoutControls() {
 	moveWindow(progressTextHWND, margin, progressY, labelW, progressHeight)
 	moveWindow(progressHWND, px, progressY, pw, progressHeight)
 	moveWindow(progressLblHWND, px+pw+labelGap, progressY, percentW, progressHeight)
+	moveWindow(totalTextHWND, margin, totalY, labelW, progressHeight)
+	moveWindow(totalHWND, px, totalY, pw, progressHeight)
+	moveWindow(totalLblHWND, px+pw+labelGap, totalY, percentW, progressHeight)
+	moveWindow(statsHWND, px, statsY, pw, statsHeight)
 
 	spacing := int32(8)
-	leftBlock := margin + 4*btnWidth + 3*spacing
+	leftBlock := margin + 5*btnWidth + 4*spacing
 	exitX := w - margin - btnWidth
 	startX := exitX - spacing - btnWidth
 	if startX <= leftBlock+spacing {
-		spacing = maxInt32(4, (w-2*margin-6*btnWidth)/7)
-		leftBlock = margin + 4*btnWidth + 3*spacing
+		spacing = maxInt32(4, (w-2*margin-7*btnWidth)/8)
+		leftBlock = margin + 5*btnWidth + 4*spacing
 		exitX = w - margin - btnWidth
 		startX = exitX - spacing - btnWidth
 		if startX <= leftBlock+spacing {
@@ -455,6 +855,8 @@ func layoutControls() {
 	moveWindow(btnCopyHWND, x, btnY, btnWidth, btnHeight)
 	x += btnWidth + spacing
 	moveWindow(btnSaveHWND, x, btnY, btnWidth, btnHeight)
+	x += btnWidth + spacing
+	moveWindow(btnWatchHWND, x, btnY, btnWidth, btnHeight)
 	moveWindow(btnStartHWND, startX, btnY, btnWidth, btnHeight)
 	moveWindow(btnExitHWND, exitX, btnY, btnWidth, btnHeight)
 }
@@ -468,6 +870,16 @@ func moveWindow(h hwnd, x, y, w, ht int32) {
 func handleCommand(wParam uintptr) {
 	id := int32(wParam & 0xFFFF)
 	switch id {
+	case idChkSize, idChkTime, idChkUpper, idChkLock, idChkCache:
+		persistSettings()
+	case idComboFmt, idComboEnc:
+		if wParam>>16 == CBN_SELCHANGE {
+			persistSettings()
+		}
+	case idComboLang:
+		if wParam>>16 == CBN_SELCHANGE {
+			onLanguageChange()
+		}
 	case idBtnBrowse:
 		onBrowse()
 	case idBtnClear:
@@ -479,15 +891,48 @@ func handleCommand(wParam uintptr) {
 	case idBtnStart:
 		startWorker()
 	case idBtnExit:
+		persistSettings()
 		procPostQuitMessage.Call(0)
+	case idBtnRemove:
+		removed := taskQueue.Remove(selectedQueueIDs()...)
+		if len(removed) > 0 {
+			pendingIDs = []int{}
+			files, bytes := pendingTotals(removed)
+			batch.Add(-files, -bytes)
+		}
+	case idBtnUp:
+		moveSelected(-1)
+	case idBtnDown:
+		moveSelected(1)
+	case idBtnFirst:
+		ids := selectedQueueIDs()
+		if taskQueue.Prioritize(ids...) > 0 {
+			pendingIDs = ids
+		}
+	case idBtnText:
+		onHashText()
+	case idBtnWatch:
+		onWatch()
+	case idBtnPaste:
+		if text, ok := getClipboardText(); ok {
+			setLabel(expEditHWND, strings.TrimSpace(text))
+		}
+	case idBtnRetry:
+		if retried := taskQueue.RetryFailed(); len(retried) > 0 {
+			appendOutput(tr("msg.retry", len(retried)))
+			batch.Add(pendingTotals(retried))
+			startWorker()
+		}
 	}
 }
 
 func onBrowse() {
-	path, ok := openFileDialog("选择要校验的文件")
+	path, ok := openFileDialog(tr("dlg.open"), guiSettings.LastOpenDir)
 	if !ok {
 		return
 	}
+	guiSettings.LastOpenDir = filepath.Dir(path)
+	persistSettings()
 	enqueueExpanded([]string{path})
 }
 
@@ -509,82 +954,275 @@ func onCopy() {
 }
 
 func onSave() {
-	path, ok := saveFileDialog("保存校验结果", "sm3_result.txt")
+	path, ok := saveFileDialog(tr("dlg.save"), "sm3_result.txt", guiSettings.LastSaveDir)
 	if !ok {
 		return
 	}
+	guiSettings.LastSaveDir = filepath.Dir(path)
+	persistSettings()
 	outputMu.Lock()
 	text := outputText
 	outputMu.Unlock()
-	_ = os.WriteFile(path, []byte(text), 0644)
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		showError(tr("msg.saveFailed", err))
+	}
+}
+
+// textInputChoices 是文本行下拉框的选项（也是设置中保存的值）：各文本编码，然后是十六进制和 Base64。
+func textInputChoices() []string {
+	return append(append([]string{}, textEncodingNames...), "hex", "Base64")
+}
+
+func textInputLabels() []string {
+	labels := textInputChoices()
+	labels[len(textEncodingNames)] = tr("input.hex")
+	return labels
+}
+
+// onHashText 计算输入框中的文本；下拉框前几项为文本编码，最后两项为十六进制和 Base64。
+func onHashText() {
+	text := getWindowText(textEditHWND)
+	sel := int(sendMessage(textEncHWND, CB_GETCURSEL, 0, 0))
+	var data []byte
+	var err error
+	var kind string
+	switch {
+	case sel == len(textEncodingNames):
+		data, err = decodeHexInput(text)
+		kind = tr("input.hex")
+	case sel == len(textEncodingNames)+1:
+		data, err = decodeBase64Input(text)
+		kind = "Base64"
+	default:
+		if sel < 0 {
+			sel = 0
+		}
+		enc := textEncoding(sel)
+		data, err = encodeText(text, enc)
+		kind = enc.String()
+	}
+	if err != nil {
+		appendOutput(tr("msg.error", err))
+		return
+	}
+	digest := sm3Hex(data)
+	lines := []string{
+		tr("msg.textResult", kind, len(data), text),
+		fmt.Sprintf("SM3: %s", formatDigest(digest, selectedDigestFormat(), isChecked(chkUpperHWND))),
+	}
+	if line, _ := compareExpected(getWindowText(expEditHWND), digest); line != "" {
+		lines = append(lines, line)
+	}
+	appendLines(append(lines, tr("msg.done")))
+}
+
+// compareExpected 比较期望值与结果，返回要输出的一行；不一致时返回 errVerifyFailed。
+func compareExpected(expected, digest string) (string, error) {
+	if expected == "" {
+		return "", nil
+	}
+	ok, err := matchDigest(expected, digest)
+	if err != nil {
+		return tr("msg.expectedInvalid", err), nil
+	}
+	if ok {
+		return tr("msg.match"), nil
+	}
+	return tr("msg.mismatch"), errVerifyFailed
+}
+
+// detectClipboardDigest 窗口激活时检查剪贴板，期望值为空且剪贴板内容是 SM3 摘要时自动填入。
+func detectClipboardDigest() {
+	if expEditHWND == 0 || getWindowText(expEditHWND) != "" {
+		return
+	}
+	text, ok := getClipboardText()
+	if !ok {
+		return
+	}
+	text = strings.TrimSpace(text)
+	if text == "" || text == lastClipDigest || len(text) > 128 {
+		return
+	}
+	if _, err := parseDigest(text); err != nil {
+		return
+	}
+	lastClipDigest = text
+	setLabel(expEditHWND, text)
+	appendOutput(tr("msg.clipboardExpected", text))
+}
+
+func selectedDigestFormat() digestFormat {
+	sel := int(sendMessage(fmtComboHWND, CB_GETCURSEL, 0, 0))
+	if sel < 0 || sel >= len(digestFormats) {
+		return digestHex
+	}
+	return digestFormat(sel)
+}
+
+func getWindowText(h hwnd) string {
+	n, _, _ := procGetWindowTextLengthW.Call(uintptr(h))
+	buf := make([]uint16, n+1)
+	procGetWindowTextW.Call(uintptr(h), uintptr(unsafe.Pointer(&buf[0])), n+1)
+	return syscall.UTF16ToString(buf)
+}
+
+var (
+	watchMu    sync.Mutex
+	watchStop  chan struct{}
+	watchFile  *rotatingFile
+	watchRes   *watchLog
+	watchLogWG sync.WaitGroup  // 正在写入 watchRes 的计算线程，停止监视时等它们写完再关闭日志
+	watchReady []string        // 已稳定、等待界面线程加入队列的文件
+	watchPaths map[string]bool // 由监视加入队列的文件，计算后写入日志
+)
+
+// onWatch 选择目录开始监视；正在监视时停止。
+func watching() bool {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	return watchStop != nil
+}
+
+func onWatch() {
+	if watching() {
+		stopWatch()
+		return
+	}
+	dir, ok := browseFolderDialog(tr("dlg.watch"), guiSettings.LastWatchDir)
+	if !ok {
+		return
+	}
+	guiSettings.LastWatchDir = dir
+	persistSettings()
+	logPath, err := defaultWatchLogPath()
+	if err == nil {
+		watchFile, err = openRotatingFile(logPath, 10<<20, 5)
+	}
+	if err != nil {
+		showError(tr("msg.watchLogOpenFailed", err))
+		return
+	}
+	stop := make(chan struct{})
+	watchMu.Lock()
+	watchStop = stop
+	watchRes = &watchLog{w: watchFile}
+	watchPaths = map[string]bool{}
+	watchMu.Unlock()
+	opt := defaultWatchOptions
+	opt.Ignore = watchFile.owns
+	go watchDirs([]string{dir}, opt, func(p string) {
+		watchMu.Lock()
+		watchReady = append(watchReady, p)
+		watchPaths[p] = true
+		watchMu.Unlock()
+		procPostMessageW.Call(uintptr(mainHWND), MSG_WATCH, 0, 0)
+	}, appendOutput, stop)
+	setLabel(btnWatchHWND, tr("btn.stopWatch"))
+	appendOutput(tr("msg.watchStart", dir, logPath))
+}
+
+func stopWatch() {
+	watchMu.Lock()
+	if watchStop == nil {
+		watchMu.Unlock()
+		return
+	}
+	close(watchStop)
+	watchStop = nil
+	watchRes = nil
+	watchReady = nil
+	watchMu.Unlock()
+	// watchRes 置空后不会再有新的写入，等进行中的写入结束再关闭文件。
+	watchLogWG.Wait()
+	watchFile.Close()
+	setLabel(btnWatchHWND, tr("btn.watch"))
+	appendOutput(tr("msg.watchStop"))
+}
+
+// drainWatchReady 在界面线程把已稳定的文件加入队列，与拖放走同一路径。
+func drainWatchReady() {
+	watchMu.Lock()
+	paths := watchReady
+	watchReady = nil
+	watchMu.Unlock()
+	if len(paths) > 0 {
+		enqueueExpanded(paths)
+	}
+}
+
+func logWatchResult(path string, start time.Time, digest string, err error) {
+	watchMu.Lock()
+	wl := watchRes
+	fromWatch := watchPaths[path]
+	delete(watchPaths, path)
+	if wl == nil || !fromWatch {
+		watchMu.Unlock()
+		return
+	}
+	watchLogWG.Add(1)
+	watchMu.Unlock()
+	defer watchLogWG.Done()
+	if werr := wl.Write(newWatchRecord(path, start, digest, err)); werr != nil {
+		appendOutput(tr("msg.watchLogFailed", werr))
+	}
 }
 
 func enqueueExpanded(paths []string) {
-	files := expandPaths(paths)
+	files, skipped := expandPaths(paths)
+	for _, err := range skipped {
+		appendOutput(tr("msg.skipped", classifyError(err), err))
+	}
+	runSkipped.Add(int64(len(skipped)))
+	hashMetrics.observeSkipped(len(skipped))
+	logSkipped(runLogger("gui"), skipped)
 	if len(files) == 0 {
 		return
 	}
+	batch.Add(pendingTotals(taskQueue.Add(files)))
 	queueMu.Lock()
-	queue = append(queue, files...)
 	running := workerRunning
 	queueMu.Unlock()
-	appendOutput(fmt.Sprintf("加入任务: %d 个文件", len(files)))
+	appendOutput(tr("msg.enqueued", len(files)))
 	if !running {
 		startWorker()
 	}
 }
 
-func expandPaths(paths []string) []string {
-	out := []string{}
-	seen := map[string]struct{}{}
-	for _, p := range paths {
-		if p == "" {
-			continue
-		}
-		info, err := os.Stat(p)
-		if err != nil {
-			continue
-		}
-		if info.IsDir() {
-			filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
-				if err != nil || d.IsDir() {
-					return nil
-				}
-				if _, ok := seen[path]; ok {
-					return nil
-				}
-				seen[path] = struct{}{}
-				out = append(out, path)
-				return nil
-			})
-		} else {
-			if _, ok := seen[p]; ok {
-				continue
-			}
-			seen[p] = struct{}{}
-			out = append(out, p)
-		}
-	}
-	return out
-}
-
 func startWorker() {
 	queueMu.Lock()
-	if workerRunning || len(queue) == 0 {
+	if workerRunning || taskQueue.Pending() == 0 {
 		queueMu.Unlock()
 		return
 	}
 	workerRunning = true
+	expectedDigest = strings.TrimSpace(getWindowText(expEditHWND))
+	batch.Reset()
+	batch.Add(taskQueue.PendingTotals())
 	queueMu.Unlock()
 	updateButtons(false)
 	go safeProcessQueue()
 }
 
 func safeProcessQueue() {
+	var sum runSummary
+	rl := runLogger("gui")
+	runStart := time.Now()
+	logRunStart(rl, batch.Snapshot().TotalFiles, batch.Snapshot().TotalBytes)
 	defer func() {
 		if r := recover(); r != nil {
-			appendOutput(fmt.Sprintf("内部错误: %v", r))
+			appendOutput(tr("msg.internalError", r))
+			rl.Error("panic", "err", fmt.Sprint(r))
+		}
+		sum.Skipped = int(runSkipped.Swap(0))
+		closeArchives()
+		if guiCache != nil {
+			if err := guiCache.Save(); err != nil {
+				appendOutput(tr("msg.cacheSaveFailed", err))
+			}
 		}
+		appendOutput(tr("msg.runFinished", sum))
+		logRunFinish(rl, sum, time.Since(runStart))
 		queueMu.Lock()
 		workerRunning = false
 		queueMu.Unlock()
@@ -592,50 +1230,90 @@ func safeProcessQueue() {
 		procPostMessageW.Call(uintptr(mainHWND), MSG_DONE, 0, 0)
 	}()
 	for {
-		queueMu.Lock()
-		if len(queue) == 0 {
-			queueMu.Unlock()
+		e, ok := taskQueue.Next()
+		if !ok {
 			return
 		}
-		path := queue[0]
-		queue = queue[1:]
-		queueMu.Unlock()
-		processFile(path)
+		fp := fileProgress{batch: &batch}
+		start := time.Now()
+		digest, hit, err := processFile(e.Path, fp.update)
+		fp.finish(e.Size)
+		hashMetrics.observeFile(e.Size, time.Since(start), hit, err)
+		logFileResult(rl, e.Path, e.Size, digest, time.Since(start), hit, err)
+		logWatchResult(e.Path, start, digest, err)
+		sum.add(err)
+		if hit {
+			sum.Cached++
+		}
+		taskQueue.Finish(e.ID, err)
 	}
 }
 
-func processFile(path string) {
-	appendOutput(fmt.Sprintf("开始计算: %s", path))
+func processFile(path string, progress progressFunc) (digest string, cached bool, err error) {
+	appendOutput(tr("msg.hashing", path))
 	setProgress(0)
 	showSize := isChecked(chkSizeHWND)
 	showTime := isChecked(chkTimeHWND)
 	upper := isChecked(chkUpperHWND)
+	opt := defaultHashOptions
+	opt.DenyWrite = isChecked(chkLockHWND)
+	opt.IO = guiIO
+	var cm cacheMode
+	if isChecked(chkCacheHWND) {
+		cm.cache = loadGUICache()
+	}
 
 	start := time.Now()
-	res, err := computeSM3File(path, func(pct int) { procPostMessageW.Call(uintptr(mainHWND), MSG_PROGRESS, uintptr(pct), 0) })
+	res, cached, err := cachedSM3File(path, opt, cm, func(done, total int64) {
+		progress(done, total)
+		pct := 100
+		if total > 0 {
+			pct = int(done * 100 / total)
+		}
+		procPostMessageW.Call(uintptr(mainHWND), MSG_PROGRESS, uintptr(pct), 0)
+	})
 	if err != nil {
-		setError(err.Error())
-		appendOutput(fmt.Sprintf("错误: %v", err))
-		procPostMessageW.Call(uintptr(mainHWND), MSG_ERROR, 0, 0)
-		return
+		appendOutput(tr("msg.errorKind", classifyError(err), err))
+		return "", false, err
 	}
-	if upper {
-		res = strings.ToUpper(res)
-	} else {
-		res = strings.ToLower(res)
+	lines := []string{fmt.Sprintf("SM3: %s", formatDigest(res, selectedDigestFormat(), upper))}
+	if cached {
+		lines[0] += tr("msg.cached")
+	}
+	line, verr := compareExpected(expectedDigest, res)
+	if line != "" {
+		lines = append(lines, line)
 	}
-	lines := []string{fmt.Sprintf("SM3: %s", res)}
 	if showSize {
 		if st, err := os.Stat(path); err == nil {
-			lines = append(lines, fmt.Sprintf("文件大小: %d 字节", st.Size()))
+			lines = append(lines, tr("msg.fileSize", st.Size()))
 		}
 	}
 	if showTime {
-		lines = append(lines, fmt.Sprintf("耗时: %.2f s", time.Since(start).Seconds()))
+		lines = append(lines, tr("msg.elapsed", time.Since(start).Seconds()))
 	}
-	lines = append(lines, "完成。")
+	lines = append(lines, tr("msg.done"))
 	appendLines(lines)
 	procPostMessageW.Call(uintptr(mainHWND), MSG_PROGRESS, uintptr(100), 0)
+	return res, cached, verr
+}
+
+// loadGUICache 在工作线程首次需要时加载缓存，失败时本次会话不再使用缓存；缓存文件损坏时从空缓存开始。
+var guiCacheOnce sync.Once
+
+func loadGUICache() *hashCache {
+	guiCacheOnce.Do(func() {
+		path, err := defaultCachePath()
+		if err == nil {
+			guiCache, err = loadHashCache(path)
+		}
+		if err != nil && guiCache != nil {
+			appendOutput(err.Error())
+		} else if err != nil {
+			appendOutput(tr("msg.cacheUnavailable", err))
+		}
+	})
+	return guiCache
 }
 
 func setFont(h hwnd, font syscall.Handle) {
@@ -678,6 +1356,14 @@ func setProgress(pct int) {
 	setLabel(progressLblHWND, fmt.Sprintf("%d%%", pct))
 }
 
+func refreshTotal() {
+	snap := batch.Snapshot()
+	pct := snap.Percent()
+	sendMessage(totalHWND, PBM_SETPOS, uintptr(pct), 0)
+	setLabel(totalLblHWND, fmt.Sprintf("%d%%", pct))
+	setLabel(statsHWND, snap.String())
+}
+
 func setLabel(h hwnd, text string) {
 	procSetWindowTextW.Call(uintptr(h), uintptr(unsafe.Pointer(toUTF16Ptr(text))))
 }
@@ -693,6 +1379,76 @@ func updateButtons(enabled bool) {
 
 func isChecked(h hwnd) bool { return sendMessage(h, BM_GETCHECK, 0, 0) == BST_CHECKED }
 
+var (
+	guiSettings   = defaultSettings // 只在界面线程读写
+	settingsFile  string            // 为空时不保存
+	savedSettings settings
+
+	// guiIO 是启动时从设置中取得的读取策略；界面中没有对应控件，之后不再改变，计算协程读取它而不读 guiSettings。
+	guiIO ioOptions
+)
+
+// loadGUISettings 在创建窗口前读取设置；保存的位置不在任何显示器上时（例如拔掉了副屏）回到默认位置。
+func loadGUISettings() {
+	path, err := defaultSettingsPath()
+	if err != nil {
+		return
+	}
+	s, err := loadSettings(path)
+	if err != nil {
+		appendOutput(tr("msg.settingsRead", err))
+	}
+	settingsFile = settingsSavePath(path, err)
+	w := s.Window
+	r := rect{int32(w.X), int32(w.Y), int32(w.X + w.Width), int32(w.Y + w.Height)}
+	if mon, _, _ := procMonitorFromRect.Call(uintptr(unsafe.Pointer(&r)), 0); mon == 0 {
+		s.Window.X, s.Window.Y = defaultSettings.Window.X, defaultSettings.Window.Y
+	}
+	guiSettings, savedSettings = s, s
+	guiIO = s.ioOptions()
+}
+
+// persistSettings 从界面读取当前选项与窗口位置，有变化时写入设置文件。
+func persistSettings() {
+	if mainHWND == 0 || chkSizeHWND == 0 {
+		return
+	}
+	s := guiSettings
+	s.ShowSize = isChecked(chkSizeHWND)
+	s.ShowTime = isChecked(chkTimeHWND)
+	s.Upper = isChecked(chkUpperHWND)
+	s.DenyWrite = isChecked(chkLockHWND)
+	s.UseCache = isChecked(chkCacheHWND)
+	s.Format = digestFormats[selectedDigestFormat()].name
+	if sel, choices := int(sendMessage(textEncHWND, CB_GETCURSEL, 0, 0)), textInputChoices(); sel >= 0 && sel < len(choices) {
+		s.TextEncoding = choices[sel]
+	}
+	wp := windowPlacement{length: uint32(unsafe.Sizeof(windowPlacement{}))}
+	if ok, _, _ := procGetWindowPlacement.Call(uintptr(mainHWND), uintptr(unsafe.Pointer(&wp))); ok != 0 {
+		r := wp.rcNormalPosition
+		s.Window = windowRect{X: int(r.left), Y: int(r.top), Width: int(r.right - r.left), Height: int(r.bottom - r.top),
+			Maximized: wp.showCmd == SW_SHOWMAXIMIZED}
+		s.normalize()
+	}
+	guiSettings = s
+	if settingsFile == "" || s == savedSettings {
+		return
+	}
+	if err := saveSettings(settingsFile, s); err != nil {
+		appendOutput(tr("msg.settingsSaveFailed", err))
+		return
+	}
+	savedSettings = s
+}
+
+func setChecked(h hwnd, on bool) {
+	state := uintptr(BST_UNCHECKED)
+	if on {
+		state = BST_CHECKED
+	}
+	sendMessage(h, BM_SETCHECK, state, 0)
+}
+
 func refreshOutput() { outputMu.Lock(); text := outputText; outputMu.Unlock(); setEditText(text) }
 func requestRefresh() {
 	if mainHWND != 0 {
@@ -700,6 +1456,101 @@ func requestRefresh() {
 	}
 }
 
+// 队列列表为虚拟列表（LVS_OWNERDATA），文本按需从 queueView 取。
+func requestQueueRefresh() {
+	if mainHWND != 0 && queueDirty.CompareAndSwap(false, true) {
+		procPostMessageW.Call(uintptr(mainHWND), MSG_QUEUE, 0, 0)
+	}
+}
+
+// pendingIDs 在队列重排后恢复选中项。
+var pendingIDs []int
+
+func refreshQueue() {
+	queueDirty.Store(false)
+	queueView = taskQueue.Snapshot()
+	sendMessage(queueHWND, LVM_SETITEMCOUNT, uintptr(len(queueView)), LVSICF_NOINVALIDATEALL|LVSICF_NOSCROLL)
+	if pendingIDs != nil {
+		want := idSet(pendingIDs)
+		pendingIDs = nil
+		none := lvItem{stateMask: LVIS_SELECTED | LVIS_FOCUSED}
+		sendMessage(queueHWND, LVM_SETITEMSTATE, ^uintptr(0), uintptr(unsafe.Pointer(&none)))
+		first := -1
+		for i, e := range queueView {
+			if _, ok := want[e.ID]; ok {
+				sel := lvItem{state: LVIS_SELECTED, stateMask: LVIS_SELECTED}
+				if first < 0 {
+					first = i
+					sel.state |= LVIS_FOCUSED
+					sel.stateMask |= LVIS_FOCUSED
+				}
+				sendMessage(queueHWND, LVM_SETITEMSTATE, uintptr(i), uintptr(unsafe.Pointer(&sel)))
+			}
+		}
+		if first >= 0 {
+			sendMessage(queueHWND, LVM_ENSUREVISIBLE, uintptr(first), 0)
+		}
+	}
+	procInvalidateRect.Call(uintptr(queueHWND), 0, 0)
+}
+
+func onNotify(lParam uintptr) {
+	hdr := *(**nmhdr)(unsafe.Pointer(&lParam))
+	if hdr.hwndFrom != queueHWND || hdr.code != LVN_GETDISPINFOW {
+		return
+	}
+	di := *(**nmlvDispInfo)(unsafe.Pointer(&lParam))
+	if di.item.mask&LVIF_TEXT == 0 || di.item.pszText == nil || di.item.cchTextMax <= 0 {
+		return
+	}
+	i := int(di.item.iItem)
+	if i < 0 || i >= len(queueView) {
+		return
+	}
+	e := queueView[i]
+	var text string
+	switch di.item.iSubItem {
+	case 0:
+		text = fmt.Sprint(e.ID)
+	case 1:
+		text = e.Status()
+	case 2:
+		text = e.Path
+		if e.Err != "" {
+			text += "  (" + e.Err + ")"
+		}
+	}
+	u := syscall.StringToUTF16(text)
+	dst := unsafe.Slice(di.item.pszText, di.item.cchTextMax)
+	n := copy(dst[:len(dst)-1], u)
+	dst[n] = 0
+}
+
+func selectedQueueIDs() []int {
+	var ids []int
+	idx := ^uintptr(0)
+	for {
+		r := sendMessage(queueHWND, LVM_GETNEXTITEM, idx, LVNI_SELECTED)
+		i := int(int32(r))
+		if i < 0 || i >= len(queueView) {
+			break
+		}
+		ids = append(ids, queueView[i].ID)
+		idx = uintptr(i)
+	}
+	return ids
+}
+
+func moveSelected(delta int) {
+	ids := selectedQueueIDs()
+	if len(ids) == 0 {
+		return
+	}
+	if taskQueue.Move(ids[0], delta) {
+		pendingIDs = ids[:1]
+	}
+}
+
 // 拖拽处理：收集全部路径（文件/文件夹），递归展开后入队。
 func handleDrop(wParam uintptr) {
 	hDrop := wParam
@@ -724,10 +1575,13 @@ func handleDrop(wParam uintptr) {
 	}
 }
 
-func openFileDialog(title string) (string, bool) {
+func openFileDialog(title, initialDir string) (string, bool) {
 	buf := make([]uint16, 260)
-	filter := toWinFilter("所有文件(*.*)\x00*.*\x00")
+	filter := toWinFilter(tr("filter.all") + "\x00*.*\x00")
 	ofn := openFileNameW{lStructSize: uint32(unsafe.Sizeof(openFileNameW{})), hwndOwner: mainHWND, lpstrFilter: filter, lpstrFile: &buf[0], nMaxFile: uint32(len(buf)), lpstrTitle: toUTF16Ptr(title), flags: 0x00080000 | 0x00001000}
+	if initialDir != "" {
+		ofn.lpstrInitialDir = toUTF16Ptr(initialDir)
+	}
 	ret, _, _ := procGetOpenFileNameW.Call(uintptr(unsafe.Pointer(&ofn)))
 	if ret == 0 {
 		return "", false
@@ -735,11 +1589,44 @@ func openFileDialog(title string) (string, bool) {
 	return syscall.UTF16ToString(buf), true
 }
 
-func saveFileDialog(title, defaultName string) (string, bool) {
+// browseInitCallback 在对话框初始化后选中 lParam 指向的目录。
+var browseInitCallback = syscall.NewCallback(func(h hwnd, message uint32, lParam, data uintptr) uintptr {
+	if message == BFFM_INITIALIZED && data != 0 {
+		sendMessage(h, BFFM_SETSELECTIONW, 1, data)
+	}
+	return 0
+})
+
+func browseFolderDialog(title, initialDir string) (string, bool) {
+	const BIF_RETURNONLYFSDIRS = 0x0001
+	name := make([]uint16, 260)
+	bi := browseInfoW{hwndOwner: mainHWND, pszDisplayName: &name[0], lpszTitle: toUTF16Ptr(title), ulFlags: BIF_RETURNONLYFSDIRS}
+	initial := toUTF16Ptr(initialDir)
+	if initialDir != "" {
+		bi.lpfn = browseInitCallback
+		bi.lParam = uintptr(unsafe.Pointer(initial))
+	}
+	pidl, _, _ := procSHBrowseForFolderW.Call(uintptr(unsafe.Pointer(&bi)))
+	runtime.KeepAlive(initial)
+	if pidl == 0 {
+		return "", false
+	}
+	defer procCoTaskMemFree.Call(pidl)
+	buf := make([]uint16, 260)
+	if ok, _, _ := procSHGetPathFromIDListW.Call(pidl, uintptr(unsafe.Pointer(&buf[0]))); ok == 0 {
+		return "", false
+	}
+	return syscall.UTF16ToString(buf), true
+}
+
+func saveFileDialog(title, defaultName, initialDir string) (string, bool) {
 	buf := make([]uint16, 260)
 	copy(buf, utf16FromString(defaultName))
-	filter := toWinFilter("文本文件 (*.txt)\x00*.txt\x00所有文件 (*.*)\x00*.*\x00")
+	filter := toWinFilter(tr("filter.text") + "\x00*.txt\x00" + tr("filter.all") + "\x00*.*\x00")
 	ofn := openFileNameW{lStructSize: uint32(unsafe.Sizeof(openFileNameW{})), hwndOwner: mainHWND, lpstrFilter: filter, lpstrFile: &buf[0], nMaxFile: uint32(len(buf)), lpstrTitle: toUTF16Ptr(title), flags: 0x00080000}
+	if initialDir != "" {
+		ofn.lpstrInitialDir = toUTF16Ptr(initialDir)
+	}
 	ret, _, _ := procGetSaveFileNameW.Call(uintptr(unsafe.Pointer(&ofn)))
 	if ret == 0 {
 		return "", false
@@ -747,6 +1634,35 @@ func saveFileDialog(title, defaultName string) (string, bool) {
 	return syscall.UTF16ToString(buf), true
 }
 
+func getClipboardText() (string, bool) {
+	if ok, _, _ := procIsClipboardFormatAvl.Call(CF_UNICODETEXT); ok == 0 {
+		return "", false
+	}
+	if ok, _, _ := procOpenClipboard.Call(uintptr(mainHWND)); ok == 0 {
+		return "", false
+	}
+	defer procCloseClipboard.Call(0)
+	hMem, _, _ := procGetClipboardData.Call(CF_UNICODETEXT)
+	if hMem == 0 {
+		return "", false
+	}
+	ptr, _, _ := procGlobalLock.Call(hMem)
+	if ptr == 0 {
+		return "", false
+	}
+	defer procGlobalUnlock.Call(hMem)
+	p := *(**uint16)(unsafe.Pointer(&ptr))
+	var u16 []uint16
+	for i := 0; ; i++ {
+		c := *(*uint16)(unsafe.Add(unsafe.Pointer(p), i*2))
+		if c == 0 {
+			break
+		}
+		u16 = append(u16, c)
+	}
+	return string(utf16.Decode(u16)), true
+}
+
 func setClipboardText(text string) {
 	procOpenClipboard.Call(0)
 	defer procCloseClipboard.Call(0)
@@ -761,7 +1677,7 @@ func setClipboardText(text string) {
 	if ptr == 0 {
 		return
 	}
-	dst := unsafe.Slice((*byte)(unsafe.Pointer(ptr)), size)
+	dst := unsafe.Slice(*(**byte)(unsafe.Pointer(&ptr)), size)
 	src := unsafe.Slice((*byte)(unsafe.Pointer(&u16[0])), size)
 	copy(dst, src)
 	procGlobalUnlock.Call(hMem)
@@ -769,10 +1685,8 @@ func setClipboardText(text string) {
 }
 
 func showError(msg string) {
-	procMessageBoxW.Call(uintptr(mainHWND), uintptr(unsafe.Pointer(toUTF16Ptr(msg))), uintptr(unsafe.Pointer(toUTF16Ptr("提示"))), 0x10)
+	procMessageBoxW.Call(uintptr(mainHWND), uintptr(unsafe.Pointer(toUTF16Ptr(msg))), uintptr(unsafe.Pointer(toUTF16Ptr(tr("dlg.notice")))), 0x10)
 }
-func setError(msg string)  { errMu.Lock(); errText = msg; errMu.Unlock() }
-func currentError() string { errMu.Lock(); defer errMu.Unlock(); return errText }
 
 func getDefaultFont() syscall.Handle {
 	if uiFont != 0 {
@@ -855,191 +1769,10 @@ func utf16FromString(s string) []uint16 { return append(syscall.StringToUTF16(s)
 func toWinFilter(s string) *uint16      { return toUTF16Ptr(s + "\x00") }
 
 func initCommonControls() {
-	icc := initCommonControlsEx{dwSize: uint32(unsafe.Sizeof(initCommonControlsEx{})), dwICC: ICC_PROGRESS_CLASS}
+	icc := initCommonControlsEx{dwSize: uint32(unsafe.Sizeof(initCommonControlsEx{})), dwICC: ICC_PROGRESS_CLASS | ICC_LISTVIEW_CLASSES}
 	procInitCommonControlsEx.Call(uintptr(unsafe.Pointer(&icc)))
 }
 
-// ---- SM3 ----
-var sm3IV = [8]uint32{0x7380166F, 0x4914B2B9, 0x172442D7, 0xDA8A0600, 0xA96F30BC, 0x163138AA, 0xE38DEE4D, 0xB0FB0E4E}
-
-func computeSM3File(path string, progress func(int)) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-	info, _ := f.Stat()
-	length := info.Size()
-	v := sm3IV
-	var block [64]byte
-	buf := make([]byte, 256*1024)
-	bufLen := 0
-	var total int64
-	lastPct := -1
-	lastSend := time.Now()
-	if progress != nil {
-		progress(0)
-	}
-	for {
-		n, err := f.Read(buf)
-		if n > 0 {
-			total += int64(n)
-			remain := n
-			offset := 0
-			for remain > 0 {
-				toCopy := minInt(64-bufLen, remain)
-				copy(block[bufLen:], buf[offset:offset+toCopy])
-				bufLen += toCopy
-				offset += toCopy
-				remain -= toCopy
-				if bufLen == 64 {
-					sm3Compress(&v, block[:])
-					bufLen = 0
-				}
-			}
-			if length > 0 && progress != nil {
-				pct := int((total * 100) / length)
-				if pct > 100 {
-					pct = 100
-				}
-				if pct != lastPct && (pct-lastPct >= 1 || time.Since(lastSend) > 200*time.Millisecond) {
-					lastPct = pct
-					lastSend = time.Now()
-					progress(pct)
-				}
-			}
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", err
-		}
-	}
-	sm3PadAndProcess(&v, block[:], bufLen, total*8)
-	if progress != nil {
-		progress(100)
-	}
-	return sm3ToHex(v), nil
-}
-
-func sm3PadAndProcess(v *[8]uint32, block []byte, bufLen int, bitLen int64) {
-	block[bufLen] = 0x80
-	bufLen++
-	if bufLen > 56 {
-		for i := bufLen; i < 64; i++ {
-			block[i] = 0
-		}
-		sm3Compress(v, block)
-		bufLen = 0
-	}
-	for i := bufLen; i < 56; i++ {
-		block[i] = 0
-	}
-	b := uint64(bitLen)
-	block[56] = byte(b >> 56)
-	block[57] = byte(b >> 48)
-	block[58] = byte(b >> 40)
-	block[59] = byte(b >> 32)
-	block[60] = byte(b >> 24)
-	block[61] = byte(b >> 16)
-	block[62] = byte(b >> 8)
-	block[63] = byte(b)
-	sm3Compress(v, block)
-}
-
-func sm3Compress(v *[8]uint32, block []byte) {
-	var w [68]uint32
-	var w1 [64]uint32
-	for i := 0; i < 16; i++ {
-		idx := i * 4
-		w[i] = uint32(block[idx])<<24 | uint32(block[idx+1])<<16 | uint32(block[idx+2])<<8 | uint32(block[idx+3])
-	}
-	for j := 16; j < 68; j++ {
-		x := w[j-16] ^ w[j-9] ^ rotl(w[j-3], 15)
-		w[j] = p1(x) ^ rotl(w[j-13], 7) ^ w[j-6]
-	}
-	for j := 0; j < 64; j++ {
-		w1[j] = w[j] ^ w[j+4]
-	}
-	a, b, c, d := v[0], v[1], v[2], v[3]
-	e, f, g, h := v[4], v[5], v[6], v[7]
-	for j := 0; j < 64; j++ {
-		ss1 := rotl((rotl(a, 12)+e+rotl(t(j), j))&0xFFFFFFFF, 7)
-		ss2 := ss1 ^ rotl(a, 12)
-		tt1 := (ff(j, a, b, c) + d + ss2 + w1[j]) & 0xFFFFFFFF
-		tt2 := (gg(j, e, f, g) + h + ss1 + w[j]) & 0xFFFFFFFF
-		d = c
-		c = rotl(b, 9)
-		b = a
-		a = tt1
-		h = g
-		g = rotl(f, 19)
-		f = e
-		e = p0(tt2)
-	}
-	v[0] ^= a
-	v[1] ^= b
-	v[2] ^= c
-	v[3] ^= d
-	v[4] ^= e
-	v[5] ^= f
-	v[6] ^= g
-	v[7] ^= h
-}
-
-func ff(j int, x, y, z uint32) uint32 {
-	if j < 16 {
-		return x ^ y ^ z
-	}
-	return (x & y) | (x & z) | (y & z)
-}
-func gg(j int, x, y, z uint32) uint32 {
-	if j < 16 {
-		return x ^ y ^ z
-	}
-	return (x & y) | (^x & z)
-}
-func p0(x uint32) uint32 { return x ^ rotl(x, 9) ^ rotl(x, 17) }
-func p1(x uint32) uint32 { return x ^ rotl(x, 15) ^ rotl(x, 23) }
-func t(j int) uint32 {
-	if j < 16 {
-		return 0x79CC4519
-	}
-	return 0x7A879D8A
-}
-func rotl(x uint32, n int) uint32 {
-	n &= 31
-	if n == 0 {
-		return x
-	}
-	return (x << n) | (x >> (32 - n))
-}
-
-func sm3ToHex(v [8]uint32) string {
-	var out [32]byte
-	for i := 0; i < 8; i++ {
-		idx := i * 4
-		out[idx] = byte(v[i] >> 24)
-		out[idx+1] = byte(v[i] >> 16)
-		out[idx+2] = byte(v[i] >> 8)
-		out[idx+3] = byte(v[i])
-	}
-	const hex = "0123456789abcdef"
-	dst := make([]byte, 64)
-	for i, b := range out {
-		dst[i*2] = hex[b>>4]
-		dst[i*2+1] = hex[b&0x0F]
-	}
-	return string(dst)
-}
-
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
 func maxInt32(a, b int32) int32 {
 	if a > b {
 		return a
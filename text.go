@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 文本输入：按指定编码和换行方式转换为字节后计算，结果与文件计算相同。
+
+type textEncoding int
+
+const (
+	encUTF8 textEncoding = iota
+	encGB18030
+	encUTF16LE
+)
+
+var textEncodingNames = []string{"UTF-8", "GB18030", "UTF-16LE"}
+
+func (e textEncoding) String() string { return textEncodingNames[e] }
+
+func parseTextEncoding(s string) (textEncoding, error) {
+	norm := strings.ReplaceAll(strings.ToUpper(s), "_", "-")
+	for i, name := range textEncodingNames {
+		if norm == name || norm == strings.ReplaceAll(name, "-", "") {
+			return textEncoding(i), nil
+		}
+	}
+	return 0, errors.New(tr("err.textEncoding", s))
+}
+
+func encodeText(s string, e textEncoding) ([]byte, error) {
+	switch e {
+	case encGB18030:
+		return simplifiedchinese.GB18030.NewEncoder().Bytes([]byte(s))
+	case encUTF16LE:
+		u := utf16.Encode([]rune(s))
+		out := make([]byte, len(u)*2)
+		for i, c := range u {
+			out[i*2] = byte(c)
+			out[i*2+1] = byte(c >> 8)
+		}
+		return out, nil
+	}
+	return []byte(s), nil
+}
+
+type newlineMode int
+
+const (
+	newlineKeep newlineMode = iota
+	newlineLF
+	newlineCRLF
+)
+
+func parseNewlineMode(s string) (newlineMode, error) {
+	switch strings.ToLower(s) {
+	case "keep":
+		return newlineKeep, nil
+	case "lf":
+		return newlineLF, nil
+	case "crlf":
+		return newlineCRLF, nil
+	}
+	return 0, errors.New(tr("err.newline", s))
+}
+
+// normalizeText 统一换行符，trim 时去掉末尾的一个换行（如 echo 附加的换行）。
+func normalizeText(s string, m newlineMode, trim bool) string {
+	if trim {
+		s = strings.TrimSuffix(s, "\n")
+		s = strings.TrimSuffix(s, "\r")
+	}
+	switch m {
+	case newlineLF:
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+	case newlineCRLF:
+		s = strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+	}
+	return s
+}
+
+// decodeHexInput 解析十六进制输入，忽略空白、冒号和 0x 前缀。
+func decodeHexInput(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' || r == ':' {
+			return -1
+		}
+		return r
+	}, s)
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.New(tr("err.hexInput", err))
+	}
+	return b, nil
+}
+
+// decodeBase64Input 接受标准与 URL 安全字母表，带或不带填充。
+func decodeBase64Input(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New(tr("err.base64Input"))
+}
+
+type textInputKind int
+
+const (
+	inputString textInputKind = iota
+	inputHex
+	inputBase64
+	inputStdin
+)
+
+// textInput 是命令行上的一项非文件输入。
+type textInput struct {
+	kind  textInputKind
+	value string
+}
+
+func (in textInput) label() string {
+	switch in.kind {
+	case inputHex:
+		return "hex:" + in.value
+	case inputBase64:
+		return "base64:" + in.value
+	case inputStdin:
+		return "-"
+	}
+	return strconv.Quote(in.value)
+}
+
+// bytes 返回待计算的字节。标准输入默认按原始字节处理，指定了编码或换行选项时按 UTF-8 文本转换。
+func (in textInput) bytes(stdin io.Reader, enc textEncoding, nl newlineMode, trim bool) ([]byte, error) {
+	switch in.kind {
+	case inputHex:
+		return decodeHexInput(in.value)
+	case inputBase64:
+		return decodeBase64Input(in.value)
+	case inputStdin:
+		data, err := io.ReadAll(stdin)
+		if err != nil || (enc == encUTF8 && nl == newlineKeep && !trim) {
+			return data, err
+		}
+		return encodeText(normalizeText(string(data), nl, trim), enc)
+	}
+	return encodeText(normalizeText(in.value, nl, trim), enc)
+}
+
+func sm3Hex(data []byte) string {
+	d := newSM3Digest()
+	d.Write(data)
+	return sm3ToHex(d.finish())
+}
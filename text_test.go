@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// TestEncodeText 的 GB18030 期望值取自 GB 18030-2005 码表（双字节、四字节 BMP 与补充平面各一例）。
+func TestEncodeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		enc  textEncoding
+		want string
+	}{
+		{"中文", encUTF8, "e4b8ade69687"},
+		{"中文", encGB18030, "d6d0cec4"},
+		{"€", encGB18030, "a2e3"},
+		{"ı", encGB18030, "81309033"},
+		{"😀", encGB18030, "9439fc36"},
+		{"abc", encGB18030, "616263"},
+		{"", encGB18030, ""},
+		{"中a", encUTF16LE, "2d4e6100"},
+		{"😀", encUTF16LE, "3dd800de"},
+	}
+	for _, tt := range tests {
+		got, err := encodeText(tt.in, tt.enc)
+		if err != nil || hex.EncodeToString(got) != tt.want {
+			t.Errorf("encodeText(%q, %v) = %x, %v; want %s", tt.in, tt.enc, got, err, tt.want)
+		}
+	}
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package main
 
 // Helper to embed an existing app.ico and version/manifest into SM3Hash.exe using Win32 UpdateResource.
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 监视模式：目录中的文件写完（一段时间内没有新事件且大小、修改时间不再变化）后自动计算 SM3。
+// Linux 用 inotify，Windows 用 ReadDirectoryChangesW，其他平台或 -poll 时定期扫描。
+
+var (
+	errNativeWatchUnsupported = localizedError("err.nativeWatch")
+	errNotDir                 = localizedError("err.notDirectory")
+)
+
+type watchOptions struct {
+	Quiet        time.Duration // 最后一次事件后等待的时间
+	Poll         bool          // 强制使用定期扫描
+	PollInterval time.Duration
+	Initial      bool              // 启动时计算已存在的文件
+	Ignore       func(string) bool // 返回 true 的路径不处理（如日志文件本身）
+}
+
+var defaultWatchOptions = watchOptions{Quiet: 2 * time.Second, PollInterval: 2 * time.Second}
+
+// settleState 记录文件最近一次事件的时间和当时的大小、修改时间。
+type settleState struct {
+	last  time.Time
+	size  int64
+	mtime time.Time
+}
+
+// settleTracker 对同一文件的连续事件去抖，文件稳定后才交给调用方。
+type settleTracker struct {
+	quiet   time.Duration
+	pending map[string]*settleState
+}
+
+func newSettleTracker(quiet time.Duration) *settleTracker {
+	return &settleTracker{quiet: quiet, pending: map[string]*settleState{}}
+}
+
+func (t *settleTracker) Touch(path string, now time.Time) {
+	s := t.pending[path]
+	if s == nil {
+		s = &settleState{}
+		t.pending[path] = s
+	}
+	s.last = now
+	if st, err := os.Stat(path); err == nil {
+		s.size, s.mtime = st.Size(), st.ModTime()
+	}
+}
+
+// Ready 返回已安静 quiet 且两次检查之间大小、修改时间未变的普通文件；仍在变化的重新计时。
+func (t *settleTracker) Ready(now time.Time) []string {
+	var out []string
+	for path, s := range t.pending {
+		if now.Sub(s.last) < t.quiet {
+			continue
+		}
+		st, err := os.Stat(path)
+		if err != nil || !st.Mode().IsRegular() {
+			delete(t.pending, path)
+			continue
+		}
+		if st.Size() != s.size || !st.ModTime().Equal(s.mtime) || !canOpenForHash(path) {
+			s.last, s.size, s.mtime = now, st.Size(), st.ModTime()
+			continue
+		}
+		delete(t.pending, path)
+		out = append(out, path)
+	}
+	return out
+}
+
+// canOpenForHash 检查文件能否以拒绝写共享方式打开；Windows 上写入方仍持有句柄时会失败。
+func canOpenForHash(path string) bool {
+	f, err := openForHash(path, true, false)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+type pollEntry struct {
+	size  int64
+	mtime time.Time
+}
+
+// pollWatch 定期扫描 roots，把新增或大小、修改时间变化的文件发送到 events。
+func pollWatch(roots []string, interval time.Duration, events chan<- string, stop <-chan struct{}) {
+	scan := func() map[string]pollEntry {
+		m := map[string]pollEntry{}
+		files, _ := expandPaths(roots)
+		for _, f := range files {
+			if st, err := os.Stat(f); err == nil {
+				m[f] = pollEntry{st.Size(), st.ModTime()}
+			}
+		}
+		return m
+	}
+	prev := scan()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+		}
+		cur := scan()
+		for p, e := range cur {
+			if old, ok := prev[p]; !ok || old != e {
+				select {
+				case events <- p:
+				case <-stop:
+					return
+				}
+			}
+		}
+		prev = cur
+	}
+}
+
+// watchDirs 持续监视 roots，文件稳定后调用 ready，直到 stop 关闭。
+// 原生通知不可用时自动退回定期扫描，并通过 notice 说明原因。
+func watchDirs(roots []string, opt watchOptions, ready func(string), notice func(string), stop <-chan struct{}) {
+	events := make(chan string, 256)
+	poll := opt.Poll
+	if !poll {
+		if err := startNativeWatch(roots, events, stop); err != nil {
+			notice(tr("msg.watchPolling", err, opt.PollInterval))
+			poll = true
+		}
+	}
+	if poll {
+		go pollWatch(roots, opt.PollInterval, events, stop)
+	}
+	ignore := func(p string) bool { return opt.Ignore != nil && opt.Ignore(p) }
+	if opt.Initial {
+		files, _ := expandPaths(roots)
+		for _, f := range files {
+			if !ignore(f) {
+				ready(f)
+			}
+		}
+	}
+
+	tracker := newSettleTracker(opt.Quiet)
+	tick := time.NewTicker(250 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case p := <-events:
+			if !ignore(p) {
+				tracker.Touch(p, time.Now())
+			}
+		case now := <-tick.C:
+			for _, p := range tracker.Ready(now) {
+				ready(p)
+			}
+		}
+	}
+}
+
+// watchRecord 是监视日志中的一行（JSON Lines）。
+type watchRecord struct {
+	Time     time.Time `json:"time"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	SM3      string    `json:"sm3,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Duration float64   `json:"seconds"`
+}
+
+// watchLog 把结果逐行写入滚动日志。
+type watchLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *watchLog) Write(rec watchRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(append(data, '\n'))
+	return err
+}
+
+func newWatchRecord(path string, start time.Time, digest string, err error) watchRecord {
+	rec := watchRecord{Time: time.Now(), Path: path, SM3: digest, Duration: time.Since(start).Seconds()}
+	if abs, aerr := filepath.Abs(path); aerr == nil {
+		rec.Path = abs
+	}
+	if st, serr := os.Stat(path); serr == nil {
+		rec.Size = st.Size()
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+func defaultWatchLogPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "SM3Hash", "watch.jsonl"), nil
+}
+
+// runWatch 实现 "sm3hash watch 目录..."，按 Ctrl+C 结束。
+func runWatch(args []string) int {
+	flags := flag.NewFlagSet("sm3hash watch", flag.ContinueOnError)
+	upper := flags.Bool("upper", false, tr("flag.upper"))
+	formatName := flags.String("format", "hex", tr("flag.formatDisplay"))
+	logPath := flags.String("log", "", tr("flag.watchLog"))
+	logMax := flags.Int64("log-max-mb", 10, tr("flag.watchLogMaxMB"))
+	logKeep := flags.Int("log-keep", 5, tr("flag.watchLogKeep"))
+	quiet := flags.Duration("quiet", defaultWatchOptions.Quiet, tr("flag.quiet"))
+	poll := flags.Bool("poll", false, tr("flag.poll"))
+	pollInterval := flags.Duration("poll-interval", defaultWatchOptions.PollInterval, tr("flag.pollInterval"))
+	initial := flags.Bool("initial", false, tr("flag.initial"))
+	retries := flags.Int("retries", defaultHashOptions.Retries, tr("flag.retries"))
+	var ioOpt ioOptions
+	ioOpt.addFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), tr("watch.usage"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	format, err := parseDigestFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 2
+	}
+	for _, root := range flags.Args() {
+		if st, err := os.Stat(root); err != nil || !st.IsDir() {
+			if err == nil {
+				err = &fs.PathError{Op: "watch", Path: root, Err: errNotDir}
+			}
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return 2
+		}
+	}
+	if *logPath == "" {
+		if *logPath, err = defaultWatchLogPath(); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+			return 2
+		}
+	}
+	rf, err := openRotatingFile(*logPath, *logMax<<20, *logKeep)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %v\n", err)
+		return 1
+	}
+	defer rf.Close()
+	wl := &watchLog{w: rf}
+	if err := openRunLog(runLogConfigFromEnv()); err != nil {
+		fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("err.runLog", err))
+		return 2
+	}
+	defer closeRunLog()
+	rl := runLogger("watch")
+
+	opt := hashOptions{Retries: *retries, IO: ioOpt}
+	wakeup := make(chan struct{}, 1)
+	q := newJobQueue(func() {
+		select {
+		case wakeup <- struct{}{}:
+		default:
+		}
+	})
+	stop := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		close(stop)
+	}()
+
+	wopt := watchOptions{Quiet: *quiet, Poll: *poll, PollInterval: *pollInterval, Initial: *initial,
+		Ignore: func(p string) bool { return rf.owns(p) || runLogFile != nil && runLogFile.owns(p) }}
+	go watchDirs(flags.Args(), wopt,
+		func(p string) { q.Add([]string{p}) },
+		func(msg string) { fmt.Fprintf(os.Stderr, "sm3hash: %s\n", msg) },
+		stop)
+	fmt.Fprintln(os.Stderr, tr("msg.watching", flags.NArg(), *logPath))
+
+	var sum runSummary
+	runStart := time.Now()
+	rl.Info("watch start", "dirs", flags.Args())
+	for {
+		e, ok := q.Next()
+		if !ok {
+			select {
+			case <-stop:
+				fmt.Fprintln(os.Stderr, sum)
+				logRunFinish(rl, sum, time.Since(runStart))
+				return 0
+			case <-wakeup:
+			}
+			continue
+		}
+		start := time.Now()
+		digest, err := computeSM3File(e.Path, opt, nil)
+		q.Finish(e.ID, err)
+		sum.add(err)
+		logFileResult(rl, e.Path, e.Size, digest, time.Since(start), false, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.errorKind", classifyError(err), err))
+		} else {
+			fmt.Printf("%s  %s\n", formatDigest(digest, format, *upper), e.Path)
+		}
+		if err := wl.Write(newWatchRecord(e.Path, start, digest, err)); err != nil {
+			fmt.Fprintf(os.Stderr, "sm3hash: %s\n", tr("msg.watchLogFailed", err))
+		}
+		q.ClearFinished()
+	}
+}
@@ -0,0 +1,107 @@
+//go:build linux
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// startNativeWatch 用 inotify 递归监视 roots；新建的子目录自动加入，其中已有的文件也会上报。
+func startNativeWatch(roots []string, events chan<- string, stop <-chan struct{}) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	// 非阻塞描述符交给 runtime 轮询，Close 可以打断阻塞中的 Read。
+	f := os.NewFile(uintptr(fd), "inotify")
+	dirs := map[int32]string{}
+	addTree := func(root string, report bool) {
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if wd, err := syscall.InotifyAddWatch(fd, p, inotifyMask); err == nil {
+					dirs[int32(wd)] = p
+				}
+			} else if report {
+				select {
+				case events <- p:
+				case <-stop:
+				}
+			}
+			return nil
+		})
+	}
+	for _, root := range roots {
+		addTree(root, false)
+	}
+	if len(dirs) == 0 {
+		f.Close()
+		return &fs.PathError{Op: "inotify_add_watch", Path: roots[0], Err: syscall.ENOENT}
+	}
+
+	go func() {
+		<-stop
+		f.Close()
+	}()
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+				off += syscall.SizeofInotifyEvent + int(ev.Len)
+				if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+					// 内核事件队列溢出（wd 为 -1），期间的变化已丢失：重新遍历全部目录，补上漏掉的子目录监视，
+					// 并把所有文件重新上报，由稳定性检查决定何时计算。
+					for _, root := range roots {
+						addTree(root, true)
+					}
+					continue
+				}
+				dir, ok := dirs[ev.Wd]
+				if !ok {
+					continue
+				}
+				if ev.Mask&(syscall.IN_DELETE_SELF|syscall.IN_IGNORED) != 0 {
+					delete(dirs, ev.Wd)
+					continue
+				}
+				name := string(nameBytes)
+				for i := 0; i < len(name); i++ {
+					if name[i] == 0 {
+						name = name[:i]
+						break
+					}
+				}
+				if name == "" {
+					continue
+				}
+				p := filepath.Join(dir, name)
+				if ev.Mask&syscall.IN_ISDIR != 0 {
+					if ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+						addTree(p, true)
+					}
+					continue
+				}
+				select {
+				case events <- p:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
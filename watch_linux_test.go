@@ -0,0 +1,100 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startTestWatch 用 inotify 监视 dir，把稳定下来的文件发送到返回的通道。
+func startTestWatch(t *testing.T, dir string) <-chan string {
+	t.Helper()
+	ready := make(chan string, 16)
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	opt := watchOptions{Quiet: 200 * time.Millisecond, PollInterval: time.Second}
+	notice := func(msg string) { t.Errorf("unexpected fallback: %s", msg) }
+	go watchDirs([]string{dir}, opt, func(p string) { ready <- p }, notice, stop)
+	// watchDirs 在自己的协程中注册 inotify，稍等片刻再开始修改文件。
+	time.Sleep(100 * time.Millisecond)
+	return ready
+}
+
+func expectSettled(t *testing.T, ready <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-ready:
+		if got != want {
+			t.Fatalf("settled %s, want %s", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", want)
+	}
+}
+
+func expectQuiet(t *testing.T, ready <-chan string, d time.Duration) {
+	t.Helper()
+	select {
+	case got := <-ready:
+		t.Fatalf("unexpected settled file %s", got)
+	case <-time.After(d):
+	}
+}
+
+func TestNativeWatchSettles(t *testing.T) {
+	dir := t.TempDir()
+	ready := startTestWatch(t, dir)
+
+	// 分两次写入：只在最后一次写入安静下来后上报一次。
+	a := filepath.Join(dir, "a.txt")
+	f, err := os.Create(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("first ")
+	time.Sleep(100 * time.Millisecond)
+	f.WriteString("second")
+	f.Close()
+	expectSettled(t, ready, a)
+	expectQuiet(t, ready, 500*time.Millisecond)
+
+	// 新建的子目录自动加入监视。
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	x := filepath.Join(sub, "x.bin")
+	if err := os.WriteFile(x, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectSettled(t, ready, x)
+
+	// 改名后按新路径上报。
+	b := filepath.Join(dir, "b.txt")
+	if err := os.Rename(a, b); err != nil {
+		t.Fatal(err)
+	}
+	expectSettled(t, ready, b)
+
+	// 稳定前就被删除的文件不上报；随后的文件照常上报。
+	c := filepath.Join(dir, "c.tmp")
+	if err := os.WriteFile(c, []byte("temp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	d := filepath.Join(dir, "d.txt")
+	if err := os.WriteFile(d, []byte("done"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectSettled(t, ready, d)
+	expectQuiet(t, ready, 500*time.Millisecond)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package main
+
+// startNativeWatch 在其他平台不可用，由调用方退回定期扫描。
+func startNativeWatch(roots []string, events chan<- string, stop <-chan struct{}) error {
+	return errNativeWatchUnsupported
+}
@@ -0,0 +1,104 @@
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fileListDirectory = 0x0001
+	watchNotifyFilter = syscall.FILE_NOTIFY_CHANGE_FILE_NAME | syscall.FILE_NOTIFY_CHANGE_DIR_NAME |
+		syscall.FILE_NOTIFY_CHANGE_SIZE | syscall.FILE_NOTIFY_CHANGE_LAST_WRITE
+)
+
+// startNativeWatch 对每个根目录以子树方式调用 ReadDirectoryChangesW；stop 时用 CancelIoEx 打断等待。
+func startNativeWatch(roots []string, events chan<- string, stop <-chan struct{}) error {
+	var handles []syscall.Handle
+	for _, root := range roots {
+		p, err := syscall.UTF16PtrFromString(root)
+		if err != nil {
+			closeHandles(handles)
+			return err
+		}
+		h, err := syscall.CreateFile(p, fileListDirectory,
+			syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+			nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+		if err != nil {
+			closeHandles(handles)
+			return err
+		}
+		handles = append(handles, h)
+	}
+	go func() {
+		<-stop
+		for _, h := range handles {
+			syscall.CancelIoEx(h, nil)
+		}
+	}()
+	for i, h := range handles {
+		go readDirChanges(h, roots[i], events, stop)
+	}
+	return nil
+}
+
+func closeHandles(hs []syscall.Handle) {
+	for _, h := range hs {
+		syscall.CloseHandle(h)
+	}
+}
+
+func readDirChanges(h syscall.Handle, root string, events chan<- string, stop <-chan struct{}) {
+	defer syscall.CloseHandle(h)
+	buf := make([]byte, 64*1024)
+	for {
+		var n uint32
+		if err := syscall.ReadDirectoryChanges(h, &buf[0], uint32(len(buf)), true, watchNotifyFilter, &n, nil, 0); err != nil {
+			return
+		}
+		if n == 0 {
+			// 缓冲区溢出，期间的变化已丢失：把子树中的全部文件重新上报，由稳定性检查决定何时计算。
+			if !reportTree(root, events, stop) {
+				return
+			}
+			continue
+		}
+		for off := uint32(0); n > 0; {
+			info := (*syscall.FileNotifyInformation)(unsafe.Pointer(&buf[off]))
+			name := unsafe.Slice(&info.FileName, info.FileNameLength/2)
+			switch info.Action {
+			case syscall.FILE_ACTION_ADDED, syscall.FILE_ACTION_MODIFIED, syscall.FILE_ACTION_RENAMED_NEW_NAME:
+				select {
+				case events <- filepath.Join(root, syscall.UTF16ToString(name)):
+				case <-stop:
+					return
+				}
+			}
+			if info.NextEntryOffset == 0 {
+				break
+			}
+			off += info.NextEntryOffset
+		}
+	}
+}
+
+// reportTree 上报 root 下的全部文件；stop 关闭时返回 false。
+func reportTree(root string, events chan<- string, stop <-chan struct{}) bool {
+	stopped := false
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		select {
+		case events <- p:
+			return nil
+		case <-stop:
+			stopped = true
+			return filepath.SkipAll
+		}
+	})
+	return !stopped
+}